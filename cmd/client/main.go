@@ -12,10 +12,14 @@ import (
 	ui "github.com/F4c3hugg3r/Go-Chat-Server/pkg/client/UI"
 	i "github.com/F4c3hugg3r/Go-Chat-Server/pkg/client/input"
 	n "github.com/F4c3hugg3r/Go-Chat-Server/pkg/client/network"
+	t "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
 
 var (
-	url = flag.String("url", "http://localhost:8080", "HTTP Server URL")
+	url     = flag.String("url", "http://localhost:8080", "HTTP Server URL")
+	session = flag.String("session", "", "File the session is stored in to resume it after a restart, disabled if empty")
+	caFile  = flag.String("caFile", "", "PEM file with additional CA certificates trusted for HTTPS")
+	pin     = flag.String("pin", "", "Hex encoded sha256 fingerprint the server certificate has to match")
 )
 
 func init() {
@@ -24,6 +28,18 @@ func init() {
 
 func main() {
 	c := n.NewClient(*url)
+	c.SetSessionFile(*session)
+
+	err := c.ConfigureTLS(*caFile, *pin)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = c.ResumeSession()
+	if err != nil {
+		c.Output <- &t.Response{Err: err.Error()}
+	}
+
 	u := i.NewUserService(c)
 	programm := tea.NewProgram(ui.InitialModel(u))
 	interChan := make(chan os.Signal, 3)
@@ -44,6 +60,8 @@ func interruptListener(interChan chan os.Signal, c *n.Client) {
 
 	if c.Registered {
 		c.Interrupt()
+	} else {
+		c.SaveSession()
 	}
 
 	c.HttpClient.CloseIdleConnections()
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"log"
@@ -15,20 +16,52 @@ import (
 
 	api "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/api"
 	chat "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/chat"
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
 )
 
-type Config struct {
-	Port      int
-	TimeLimit time.Duration
-	maxUsers  int
-}
-
 func main() {
-	cfg := ParseFlags()
-	service := chat.NewChatService(cfg.maxUsers)
-	plugin := chat.RegisterPlugins(service)
+	path, overrides := ParseFlags()
+
+	cfg, err := config.Load(path, overrides)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	holder := config.NewHolder(cfg)
+
+	store, err := openMessageStore(cfg)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	defer store.Close()
+
+	tlsConfig, err := loadTLSConfig(cfg)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	accounts, err := chat.NewAccountStore(cfg.AccountsFile)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	groups, err := chat.NewGroupStore(cfg.GroupsFile)
+	if err != nil {
+		log.Println(err.Error())
+		return
+	}
+
+	tokens := chat.NewTokenManager(cfg.TokenSecret, time.Duration(cfg.TokenTTL))
+	service := chat.NewChatService(holder, store, tokens)
+	service.RestoreGroups(groups)
+	plugin := chat.RegisterPlugins(service, accounts, holder)
 	webRTC := chat.RegisterCallPlugins(service)
-	handler := api.NewServerHandler(service, plugin, webRTC)
+	handler := api.NewServerHandler(holder, service, plugin, webRTC)
 	wg := &sync.WaitGroup{}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -36,19 +69,23 @@ func main() {
 
 	server := &http.Server{
 		Addr:              fmt.Sprintf(":%d", cfg.Port),
-		ReadTimeout:       15 * time.Second,
-		WriteTimeout:      15 * time.Second,
-		ReadHeaderTimeout: 15 * time.Second,
+		ReadTimeout:       time.Duration(cfg.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.WriteTimeout),
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout),
 	}
 
-	setUp(server, handler, cfg.TimeLimit, wg, ctx)
+	setUp(server, handler, wg, ctx)
 
 	interChan := make(chan os.Signal, 2)
-	signal.Notify(interChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, os.Interrupt)
+	signal.Notify(interChan, os.Interrupt, syscall.SIGTERM)
+
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
 
 	wg.Add(1)
 
 	go interruptListener(interChan, server, wg, cancel, handler)
+	go reloadListener(reloadChan, holder, path, overrides, ctx)
 
 	ln, err := net.Listen("tcp", server.Addr)
 	if err != nil {
@@ -56,8 +93,12 @@ func main() {
 		return
 	}
 
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+
 	defer ln.Close()
-	log.Println("Server running on port:", cfg.Port)
+	log.Println("Server running on port:", cfg.Port, "tls:", tlsConfig != nil)
 
 	err = server.Serve(ln)
 	if err != nil {
@@ -89,8 +130,27 @@ func interruptListener(interChan chan os.Signal, server *http.Server, wg *sync.W
 	log.Println("Shutting down Server")
 }
 
+// reloadListener reloads the config file on every syscall.SIGHUP until the context cancels,
+// only settings which can safely be changed while running are applied
+func reloadListener(reloadChan chan os.Signal, holder *config.Holder, path string, overrides map[string]string, ctx context.Context) {
+	for {
+		select {
+		case <-reloadChan:
+			err := holder.Reload(path, overrides)
+			if err != nil {
+				log.Printf("config couldn't be reloaded, keeping the current one: %s", err)
+				continue
+			}
+
+			log.Println("Config reloaded")
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // setUp sets up server handlers and the inactiveClientDeleter routine, which runs until the context cancels
-func setUp(server *http.Server, handler *api.ServerHandler, timeLimit time.Duration, wg *sync.WaitGroup, ctx context.Context) {
+func setUp(server *http.Server, handler *api.ServerHandler, wg *sync.WaitGroup, ctx context.Context) {
 	server.Handler = handler.BuildMultiplexer()
 
 	wg.Add(1)
@@ -98,14 +158,21 @@ func setUp(server *http.Server, handler *api.ServerHandler, timeLimit time.Durat
 	go func() {
 		defer wg.Done()
 
-		ticker := time.NewTicker(15 * time.Second)
+		interval := time.Duration(handler.Config.Get().CleanupInterval)
+		ticker := time.NewTicker(interval)
 
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ticker.C:
-				handler.Service.InactiveObjectDeleter(timeLimit)
+				handler.Service.InactiveObjectDeleter()
+
+				// the interval may have been changed by a reload
+				if next := time.Duration(handler.Config.Get().CleanupInterval); next != interval {
+					interval = next
+					ticker.Reset(interval)
+				}
 			case <-ctx.Done():
 				return
 			}
@@ -113,14 +180,46 @@ func setUp(server *http.Server, handler *api.ServerHandler, timeLimit time.Durat
 	}()
 }
 
-// ParseFlags parses server port, maximum users and tiemout duration flags
-func ParseFlags() Config {
-	var cfg Config
-
-	flag.IntVar(&cfg.Port, "port", 8080, "HTTP Server Port")
-	flag.IntVar(&cfg.maxUsers, "maxUsers", 100, "Maximum number of active users allowed")
-	flag.DurationVar(&cfg.TimeLimit, "timeLimit", 10*time.Second, "Time limit for inactive clients in seconds")
+// ParseFlags parses the path of the config file and the flags overriding its settings. The
+// overrides are keyed by the json name of the setting and only contain flags which were set
+func ParseFlags() (string, map[string]string) {
+	defaults := config.Default()
+
+	path := flag.String("config", "", "JSON config file, settings can be overridden by CHAT_* environment variables and flags")
+	flag.Int("port", defaults.Port, "HTTP Server Port")
+	flag.Int("maxUsers", defaults.MaxUsers, "Maximum number of active users allowed")
+	flag.Int("maxBatch", defaults.MaxBatch, "Maximum number of responses delivered per batched GET request")
+	flag.Duration("timeLimit", time.Duration(defaults.TimeLimit), "Time limit for inactive clients in seconds")
+	flag.Duration("gracePeriod", time.Duration(defaults.GracePeriod), "Time a disconnected client can resume its session before it is deleted")
+	flag.String("historyFile", defaults.HistoryFile, "File the message history is appended to, kept in memory only if empty")
+	flag.Int("historySize", defaults.HistorySize, "Number of messages kept in memory for the message history")
+	flag.String("accountsFile", defaults.AccountsFile, "File the user accounts are stored in, kept in memory only if empty")
+	flag.String("groupsFile", defaults.GroupsFile, "File the persistent groups are stored in, kept in memory only if empty")
+	flag.String("tokenSecret", defaults.TokenSecret, "Secret the session tokens are signed with, a random one is generated if empty")
+	flag.Duration("tokenTTL", time.Duration(defaults.TokenTTL), "Time a session token is valid before it has to be refreshed")
+	flag.String("adminKey", defaults.AdminKey, "Key required by the admin api, the admin api is disabled if empty")
+	flag.String("tlsCert", defaults.TLSCert, "PEM certificate file, serves HTTPS together with -tlsKey")
+	flag.String("tlsKey", defaults.TLSKey, "PEM private key file of the -tlsCert certificate")
+	flag.Bool("tlsSelfSigned", defaults.TLSSelfSigned, "Serve HTTPS with a generated self-signed certificate (development only)")
 	flag.Parse()
 
-	return cfg
+	overrides := make(map[string]string)
+
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			overrides[f.Name] = f.Value.String()
+		}
+	})
+
+	return *path, overrides
+}
+
+// openMessageStore opens the file backed message store if a history file is configured
+// and falls back to an in-memory store otherwise
+func openMessageStore(cfg *config.Config) (chat.MessageStore, error) {
+	if cfg.HistoryFile == "" {
+		return chat.NewMemoryStore(cfg.HistorySize), nil
+	}
+
+	return chat.NewFileStore(cfg.HistoryFile, cfg.HistorySize)
 }
@@ -2,6 +2,7 @@ package UI
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -98,6 +99,11 @@ func (m model) Update(rsp tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.refreshTable("")
 
+	case InviteResultMsg:
+		if rsp.Expired {
+			m.DisplayMessage(green.Render(fmt.Sprintf("- Die Einladung in die Gruppe %s ist abgelaufen -", rsp.Group)))
+		}
+
 	case t.ClientsChangeSignal:
 		m.HandleClientsChangeSignal(rsp)
 
@@ -111,10 +117,14 @@ func (m model) Update(rsp tea.Msg) (tea.Model, tea.Cmd) {
 	case *t.Response:
 		m.HandleResponse(rsp)
 
-		if strings.Contains(rsp.Content, t.ReceiveCall) {
+		if isIncomingCall(rsp) {
 			return m, tea.Batch(tiCmd, vpCmd, loCmd, tbCmd, mTbCmd, m.ReceiveCall(rsp), m.waitForExternalResponse())
 		}
 
+		if invite := groupInvite(rsp); invite != nil {
+			return m, tea.Batch(tiCmd, vpCmd, loCmd, tbCmd, mTbCmd, m.ReceiveInvite(invite), m.waitForExternalResponse())
+		}
+
 		return m, tea.Batch(tiCmd, vpCmd, loCmd, tbCmd, mTbCmd, m.waitForExternalResponse())
 
 	case tea.WindowSizeMsg:
@@ -218,6 +228,24 @@ func (m *model) ReceiveCall(rsp *t.Response) tea.Cmd {
 	}
 }
 
+// ReceiveInvite prompts the invitation until it is answered, replaced by a newer one or expires
+func (m *model) ReceiveInvite(invite *t.JsonInvite) tea.Cmd {
+	answered := m.userService.Client.PromptInvite(invite.Group.GroupId)
+	m.logChan <- t.Log{Text: fmt.Sprintf("ReceiveInvite started for group: %s", invite.Group.GroupId), Method: "ReceiveInvite"}
+
+	return func() tea.Msg {
+		select {
+		case <-time.After(time.Until(invite.Expires)):
+			m.logChan <- t.Log{Text: "Invite expired", Method: "ReceiveInvite"}
+			m.userService.Client.ExpireInvite(invite.Group.GroupId)
+			return InviteResultMsg{Group: invite.Group.Name, Expired: true}
+		case accepted, ok := <-answered:
+			m.logChan <- t.Log{Text: fmt.Sprintf("Invite answered: %v, replaced: %v", accepted, !ok), Method: "ReceiveInvite"}
+			return InviteResultMsg{Group: invite.Group.Name}
+		}
+	}
+}
+
 func (m *model) ToggleLogs() {
 	switch m.logViewport.Height {
 	case 0:
@@ -308,10 +336,16 @@ func (m *model) RenderTitle(title string, param []string) {
 
 		case strings.Contains(title, t.RegisterFlag):
 			title = titleStyle.Render(fmt.Sprintf(RegisterTitle,
-				turkis.Bold(true).Render(param[0])))
+				turkis.Bold(true).Render(param[0])) + m.groupsLine())
+
+		// the topic of the group is shown next to its name
+		case strings.Contains(title, t.AddGroupFlag) && len(param) > 2 && param[2] != "":
+			title = titleStyle.Render(fmt.Sprintf(TopicTitle, turkis.Bold(true).Render(param[0]), turkis.Bold(true).Render(param[1]),
+				turkis.Italic(true).Render(param[2])) + m.groupsLine())
 
 		case strings.Contains(title, t.AddGroupFlag):
-			title = titleStyle.Render(fmt.Sprintf(GroupTitle, turkis.Bold(true).Render(param[0]), turkis.Bold(true).Render(param[1])))
+			title = titleStyle.Render(fmt.Sprintf(GroupTitle, turkis.Bold(true).Render(param[0]), turkis.Bold(true).Render(param[1])) +
+				m.groupsLine())
 		}
 	}
 
@@ -320,6 +354,36 @@ func (m *model) RenderTitle(title string, param []string) {
 	m.refreshViewPortAndTableHeight(heightDiff)
 }
 
+// groupsLine lists the lobby and the groups of the client for the title with the number of unread
+// messages behind the inactive ones, it is empty if the client isn't in a group
+func (m *model) groupsLine() string {
+	groups := m.userService.Client.GetGroups()
+	if len(groups) < 1 {
+		return ""
+	}
+
+	activeId := m.userService.Client.GetGroupId()
+
+	names := []string{}
+	for _, group := range append([]*t.JsonGroup{{Name: "Lobby"}}, groups...) {
+		name := group.Name
+		if group.GroupId != "" {
+			name = groupName(group.Name, group.Protected)
+		}
+
+		switch unread := m.userService.Client.Unread(group.GroupId); {
+		case group.GroupId == activeId:
+			name = turkis.Bold(true).Render(name)
+		case unread > 0:
+			name = fmt.Sprintf("%s (%d)", name, unread)
+		}
+
+		names = append(names, name)
+	}
+
+	return "\n" + fmt.Sprintf(GroupsLine, strings.Join(names, faint.Render(" | ")))
+}
+
 //
 // refresh functions
 //
@@ -386,6 +450,9 @@ func (m *model) HandleMute(toMute string) {
 }
 
 func (m *model) HandleTableSelect() {
+	m.textinput.SetValue(fmt.Sprintf("@%s ", m.table.SelectedRow()[0]))
+	m.textinput.CursorEnd()
+
 	message := turkis.BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).BorderForeground(purple.GetForeground()).Render(
 		fmt.Sprintf("%s%s\n%s%s\n%s%s",
 			blue.Render("Name:		"),
@@ -440,9 +507,20 @@ func (m *model) HandleClientsChangeSignal(rsp t.ClientsChangeSignal) error {
 	m.tableValues.SetClients(clients, nil)
 
 	m.refreshTable("")
+	m.refreshSuggestions()
 	return nil
 }
 
+// refreshSuggestions completes commands with the names of the known users and groups
+func (m *model) refreshSuggestions() {
+	groups := slices.Clone(m.listedGroups)
+	for _, group := range m.userService.Client.GetGroups() {
+		groups = append(groups, group.Name)
+	}
+
+	m.textinput.SetSuggestions(m.userService.Suggestions(m.tableValues.Names(), groups))
+}
+
 // HandleWindowResize handles rezising of the terminal window by updating all models sizes
 func (m *model) HandleWindowResize(rsp *tea.WindowSizeMsg) {
 	m.viewport.Width = rsp.Width / 6 * 4
@@ -495,14 +573,39 @@ func (m *model) HandleResponse(rsp *t.Response) {
 }
 
 // HandleMessage hanbles the input message by saving it for the inputHistory and
-// executeing the fitting userService method
+// executeing the fitting userService method, inputs containing passwords are not saved
 func (m *model) HandleMessage() {
-	m.inH.SaveInput(m.textinput.Value())
+	if !hasPassword(m.textinput.Value()) {
+		m.inH.SaveInput(m.textinput.Value())
+	}
 
 	m.userService.Executor(m.textinput.Value())
 	m.textinput.Reset()
 }
 
+// hasPassword reports whether the input contains a password like the account commands
+// or joining and creating protected groups
+func hasPassword(input string) bool {
+	if strings.HasPrefix(input, "/login") || strings.HasPrefix(input, "/signup") {
+		return true
+	}
+
+	fields := strings.Fields(input)
+	if len(fields) < 2 || fields[0] != "/group" {
+		return false
+	}
+
+	switch fields[1] {
+	case "join":
+		return len(fields) > 3
+	case "create":
+		_, _, password, _ := t.SplitGroupOptions(strings.Join(fields[2:], " "))
+		return password != ""
+	}
+
+	return false
+}
+
 //
 //	poll channels
 //
@@ -13,8 +13,10 @@ import (
 
 const Gap = "\n\n"
 const RegisterTitle = "Du bist registriert %s!"
-const UnregisterTitle = "Willkommen im Chatraum! \nSchreibe '/register {name}' und '/help'"
+const UnregisterTitle = "Willkommen im Chatraum! \nSchreibe '/register {name}', '/login {name} {password}' oder '/signup {name} {password}' und '/help'"
 const GroupTitle = "%s, du bist in der Gruppe %s!"
+const TopicTitle = "%s, du bist in der Gruppe %s – %s!"
+const GroupsLine = "Gruppen: %s"
 const WindowResizeFlag = "windowResize"
 const RegisterOutput = "-> Du kannst nun Nachrichten schreiben oder Commands ausführen" +
 	"\n		'/help' → Befehle anzeigen" +
@@ -121,6 +123,8 @@ type model struct {
 	helpModel       help.Model
 	keyMap          keyMap
 	err             error
+	// names of the groups of the last group list
+	listedGroups []string
 
 	table           table.Model
 	tableValues     *Table
@@ -143,3 +147,9 @@ type InputHistory struct {
 type CallResultMsg struct {
 	Accepted bool
 }
+
+// InviteResultMsg is sent when the prompt of an invitation ends
+type InviteResultMsg struct {
+	Group   string
+	Expired bool
+}
@@ -1,162 +1,523 @@
 package UI
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	t "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// evaluateResponse evaluates an incoming Response and returns the
-// corresponding rendered string
+// evaluateResponse evaluates an incoming Response by its Kind and returns the
+// corresponding rendered string, responses of older servers are upgraded first
 func (m *model) EvaluateReponse(rsp *t.Response) string {
-	var rspString string
+	rsp = t.Upgrade(rsp)
 
-	switch {
+	if m.inInactiveGroup(rsp) {
+		return m.evaluateInactiveGroup(rsp)
+	}
+
+	switch rsp.Kind {
 	// error output
-	case rsp.Err != "":
+	case t.KindError:
 		if rsp.Err == t.IgnoreResponseTag {
 			return ""
 		}
 		return red.Render(rsp.Err)
 
 	// Users output
-	case strings.Contains(rsp.RspName, t.UsersFlag):
+	case t.KindUsers:
+		var users t.UsersPayload
+		if err := rsp.DecodePayload(&users); err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting json to clients", err))
+		}
+
+		clientsJson, err := json.Marshal(users.Clients)
+		if err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting clients to json", err))
+		}
+
 		m.userService.Client.ClientChangeSignalChan <- t.ClientsChangeSignal{
-			ClientsJson: rsp.Content,
+			ClientsJson: string(clientsJson),
 		}
-		m.logChan <- t.Log{Text: fmt.Sprintf("clients = %s", rsp.Content)}
+		m.logChan <- t.Log{Text: fmt.Sprintf("clients = %s", clientsJson)}
 
 		return ""
 
-	// empty output
-	case rsp.Content == "", rsp.Content == "null":
-		return ""
+	// history output
+	case t.KindHistory:
+		var history t.HistoryPayload
+		if err := rsp.DecodePayload(&history); err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting json to messages", err))
+		}
+
+		return RenderHistory(history.Messages)
 
 	// register output
-	case strings.Contains(rsp.Content, t.RegisterFlag):
+	case t.KindRegistered:
 		m.RenderTitle(t.RegisterFlag, []string{m.userService.Client.GetName()})
 		m.userService.Executor("/users")
 
 		return purple.BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).
 			BorderForeground(purple.GetForeground()).Render(blue.Render(RegisterOutput))
 
-	// server output
-	case rsp.RspName == "":
-		rspString = fmt.Sprintf("%s", blue.Render(rsp.Content))
+	// unregister output
+	case t.KindUnregistered:
+		m.RenderTitle(t.UnregisterFlag, nil)
+		m.userService.Client.DeletePeers("", true, true)
+		m.userService.Client.ClientChangeSignalChan <- t.ClientsChangeSignal{
+			CallState: t.UnregisterFlag,
+		}
 
-		// unregister output
-		if strings.Contains(rsp.Content, t.UnregisterFlag) {
-			m.RenderTitle(t.UnregisterFlag, nil)
-			m.userService.Client.DeletePeers("", true, true)
-			m.userService.Client.ClientChangeSignalChan <- t.ClientsChangeSignal{
-				CallState: t.UnregisterFlag,
-			}
+		return blue.Render(t.UnregisterFlag)
+
+	// server output
+	case t.KindNotice:
+		var notice t.NoticePayload
+		if err := rsp.DecodePayload(&notice); err != nil || notice.Text == "" || notice.Text == "null" {
+			return ""
 		}
 
-		return rspString
+		return blue.Render(notice.Text)
 
 	// one user left output
-	case strings.Contains(rsp.RspName, t.UserRemoveFlag):
-		if m.userService.Client.GetGroupId() != "" {
-			m.userService.Executor("/group users")
-		} else {
-			m.userService.Executor("/users")
+	case t.KindUserLeft:
+		var user t.UserPayload
+		if err := rsp.DecodePayload(&user); err != nil {
+			return ""
 		}
-		return fmt.Sprintf("%s %s", purple.Render(rsp.Content), blue.Faint(true).Render("hat den Chat verlassen"))
+
+		m.refreshUsers()
+		return fmt.Sprintf("%s %s", purple.Render(user.Name), blue.Faint(true).Render("hat den Chat verlassen"))
 
 	// one user joined output
-	case strings.Contains(rsp.RspName, t.UserAddFlag):
-		if m.userService.Client.GetGroupId() != "" {
-			m.userService.Executor("/group users")
-		} else {
-			m.userService.Executor("/users")
+	case t.KindUserJoined:
+		var user t.UserPayload
+		if err := rsp.DecodePayload(&user); err != nil {
+			return ""
 		}
-		return fmt.Sprintf("%s %s", purple.Render(rsp.Content), blue.Faint(true).Render("ist dem Chat beigetreten"))
+
+		m.refreshUsers()
+		return fmt.Sprintf("%s %s", purple.Render(user.Name), blue.Faint(true).Render("ist dem Chat beigetreten"))
+
+	// one user changed its name
+	case t.KindUserRenamed:
+		var user t.UserPayload
+		if err := rsp.DecodePayload(&user); err != nil {
+			return ""
+		}
+
+		m.refreshUsers()
+
+		if user.ClientId == m.userService.Client.GetClientId() {
+			m.userService.Client.Rename(user.Name)
+			m.refreshTitle()
+			return blue.Render(fmt.Sprintf("Du heißt jetzt %s", purple.Render(user.Name)))
+		}
+
+		return fmt.Sprintf("%s %s %s", purple.Render(user.OldName), blue.Faint(true).Render("heißt jetzt"), purple.Render(user.Name))
 
 	// addGroup output
-	case strings.Contains(rsp.RspName, t.AddGroupFlag):
-		group, err := m.userService.HandleAddGroup(rsp.Content)
-		if err != nil {
+	case t.KindGroupJoined:
+		var joined t.GroupPayload
+		if err := rsp.DecodePayload(&joined); err != nil || joined.Group == nil {
 			return red.Render(fmt.Sprintf("%v: error formatting json to group", err))
 		}
 
-		m.RenderTitle(t.AddGroupFlag, []string{m.userService.Client.GetName(), group.Name})
+		m.userService.HandleAddGroup(joined.Group)
+
+		m.refreshTitle()
+		m.refreshSuggestions()
 		m.userService.Executor("/group users")
 
 		return purple.BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).
-			BorderForeground(purple.GetForeground()).Render(fmt.Sprintf("%s %s\n%s",
+			BorderForeground(purple.GetForeground()).Render(fmt.Sprintf("%s %s\n%s\n%s",
 			blue.Render("-> Du bist nun Teil der Gruppe"),
-			turkis.Render(group.Name),
+			turkis.Render(joined.Group.Name),
 			blue.Faint(true).Render("Private Nachrichten kannst du weiterhin außerhalb verschicken"),
+			blue.Faint(true).Render("Mit '/group switch [name]' wechselst du zwischen deinen Gruppen und der Lobby"),
 		))
 
+	// switchGroup output
+	case t.KindGroupSwitched:
+		var switched t.GroupPayload
+		if err := rsp.DecodePayload(&switched); err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting json to group", err))
+		}
+
+		unread := m.userService.Client.SwitchGroup(switched.Group)
+		m.refreshTitle()
+		m.refreshUsers()
+
+		if switched.Group == nil {
+			// private messages are shown in every room, so only the lobby messages are replayed
+			if unread > 0 {
+				m.userService.Executor(fmt.Sprintf("/history %d --lobby", min(unread, maxUnreadHistory)))
+			}
+			return blue.Render("-> Du bist nun in der Lobby")
+		}
+
+		// the messages received while the group wasn't active are replayed
+		if unread > 0 {
+			m.userService.Executor(fmt.Sprintf("/group history %d", min(unread, maxUnreadHistory)))
+		}
+
+		return fmt.Sprintf("%s %s", blue.Render("-> Du bist nun in der Gruppe"), turkis.Render(switched.Group.Name))
+
+	// renamed group or new topic output
+	case t.KindGroupUpdated:
+		var updated t.GroupPayload
+		if err := rsp.DecodePayload(&updated); err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting json to group", err))
+		}
+
+		if updated.Group == nil {
+			return blue.Render(updated.Reason)
+		}
+
+		m.userService.Client.UpdateGroup(updated.Group)
+		m.refreshTitle()
+		m.refreshSuggestions()
+
+		if updated.Group.GroupId != m.userService.Client.GetGroupId() {
+			return fmt.Sprintf("%s %s", turkis.Faint(true).Render(fmt.Sprintf("[%s]", updated.Group.Name)), blue.Render(updated.Reason))
+		}
+
+		return blue.Render(updated.Reason)
+
 	// leaveGroup output
-	case strings.Contains(rsp.RspName, t.LeaveGroupFlag):
-		m.userService.Client.UnsetGroupId()
-		m.RenderTitle(t.RegisterFlag, []string{m.userService.Client.GetName()})
-		m.userService.Client.DeletePeers("", true, true)
+	case t.KindGroupLeft:
+		var left t.GroupPayload
+		rsp.DecodePayload(&left)
+
+		groupId := ""
+		if left.Group != nil {
+			groupId = left.Group.GroupId
+		}
+
+		// a call ends with the group it takes place in, older servers don't tell which group was left
+		if callGroupId := m.userService.Client.CallGroupId(); groupId == "" || callGroupId == "" || callGroupId == groupId {
+			m.userService.Client.DeletePeers("", true, true)
+		}
+
+		wasActive := m.userService.Client.RemoveGroup(groupId)
+		m.refreshTitle()
+		m.refreshSuggestions()
+
+		if !wasActive {
+			if left.Reason != "" {
+				return green.Render(fmt.Sprintf("- %s -", left.Reason))
+			}
+
+			return blue.Render(fmt.Sprintf("Du bist nicht mehr in der Gruppe %s", left.Group.Name))
+		}
+
 		m.userService.Executor("/users")
 
-		return purple.BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).
+		output := purple.BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).
 			BorderForeground(purple.GetForeground()).
 			Render(blue.Render(RegisterOutput))
 
+		// the reason tells if you were removed by a moderator
+		if left.Reason != "" {
+			output = fmt.Sprintf("%s\n%s", green.Render(fmt.Sprintf("- %s -", left.Reason)), output)
+		}
+
+		return output
+
+	// invitation output
+	case t.KindGroupInvite:
+		invite := groupInvite(rsp)
+		if invite == nil {
+			return red.Render(fmt.Sprintf("%v: error formatting json to invite", t.ErrParsing))
+		}
+
+		return fmt.Sprintf("%s%s", green.Render(fmt.Sprintf("%s lädt dich in die Gruppe %s ein! Antworte innerhalb von %v:",
+			invite.Inviter, invite.Group.Name, time.Until(invite.Expires).Round(time.Second))),
+			blue.Render("\n		-> '/group accept' um anzunehmen"+
+				"\n		-> '/group decline' um abzulehnen"))
+
+	// Receive webRTC signal (Offer SDP Signal, Answer SDP Signal, ICE Candidate or failed connection)
+	case t.KindSignal:
+		return m.evaluateSignal(rsp)
+
+	// slice output
+	case t.KindTable:
+		var table t.TablePayload
+		if err := rsp.DecodePayload(&table); err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting json to table", err))
+		}
+
+		if table.Name == "Group List" {
+			m.listedGroups = groupNames(table.Rows)
+			m.refreshSuggestions()
+			table.Rows = markProtectedGroups(table.Rows)
+		}
+
+		rows, err := json.Marshal(table.Rows)
+		if err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting table to json", err))
+		}
+
+		output, err := JSONToTable(string(rows))
+		if err != nil {
+			return red.Render(fmt.Sprintf("%v: error formatting json to table", err))
+		}
+
+		return output
+
+	// message output
+	case t.KindChat:
+		var chat t.ChatPayload
+		if err := rsp.DecodePayload(&chat); err != nil || chat.Content == "" {
+			return ""
+		}
+
+		name := chat.Sender
+		if chat.Private {
+			name = fmt.Sprintf("[%s]", chat.Sender)
+		}
+
+		return fmt.Sprintf("%s: %s", turkis.Render(name), chat.Content)
+
+	// plugin output
+	case t.KindResult:
+		var result t.ResultPayload
+		if err := rsp.DecodePayload(&result); err != nil {
+			return ""
+		}
+
+		return fmt.Sprintf("%s: %s", turkis.Render(result.Name), result.Content)
+	}
+
+	m.logChan <- t.Log{Text: fmt.Sprintf("unknown response kind %s", rsp.Kind)}
+
+	return ""
+}
+
+// evaluateSignal evaluates a call or webRTC signal and returns the corresponding rendered string
+func (m *model) evaluateSignal(rsp *t.Response) string {
+	var signal t.SignalPayload
+	if err := rsp.DecodePayload(&signal); err != nil {
+		return red.Render(fmt.Sprintf("%v: error formatting json to signal", err))
+	}
+
 	// Rollback/Delete Peer output
-	case strings.Contains(rsp.RspName, t.FailedConnectionFlag):
+	if signal.Signal == t.FailedConnectionFlag {
 		if len(m.userService.Client.Peers) < 1 {
 			return ""
 		}
 
 		m.userService.Client.DeletePeers(rsp.ClientId, false, false)
-		if m.userService.Client.GetGroupId() != "" {
-			m.userService.Executor("/group users")
-		} else {
-			m.userService.Executor("/users")
-		}
+		m.refreshUsers()
 
 		return fmt.Sprintf("%s %s %s", blue.Render("Anruf mit"), purple.Faint(true).Render(rsp.ClientId), blue.Render("beendet"))
+	}
+
+	m.logChan <- t.Log{Text: "webrtc related signal detected"}
+
+	switch signal.Data {
+	case t.ReceiveCall:
+		return fmt.Sprintf("%s%s", green.Render("Du wirst angerufen! Antworte innerhalb von 15sek:"),
+			blue.Render("\n		-> '/call accept' um anzunehmen"+
+				"\n		-> '/call deny' um abzulehnen"))
+
+	case t.CallAccepted:
+		m.userService.Client.HandleSignal(rsp, false, true)
+		return green.Render("Dein Anruf wurde angenommen, verbinde...")
 
-	// Receive webRTC signal (Offer SDP Signal, Answer SDP Signal or ICE Candidate)
-	case strings.Contains(rsp.RspName, t.OfferSignalFlag),
-		strings.Contains(rsp.RspName, t.AnswerSignalFlag),
-		strings.Contains(rsp.RspName, t.ICECandidateFlag),
-		strings.Contains(rsp.RspName, t.InitializeSignalFlag):
-		m.logChan <- t.Log{Text: "webrtc related signal detected"}
+	case t.CallDenied:
+		m.userService.Client.DeletePeers(rsp.ClientId, false, false)
+		return green.Render("- Dein Anruf wurde abgelehnt -")
+	}
+
+	m.userService.Client.HandleSignal(rsp, false, false)
+
+	return ""
+}
+
+// isIncomingCall reports whether the response is the signal of an incoming call
+func isIncomingCall(rsp *t.Response) bool {
+	var signal t.SignalPayload
+	if t.Upgrade(rsp).Kind != t.KindSignal || rsp.DecodePayload(&signal) != nil {
+		return false
+	}
+
+	return signal.Data == t.ReceiveCall
+}
+
+// groupInvite returns the invitation of the response or nil if it is none
+func groupInvite(rsp *t.Response) *t.JsonInvite {
+	var payload t.InvitePayload
+	if t.Upgrade(rsp).Kind != t.KindGroupInvite || rsp.DecodePayload(&payload) != nil || payload.Invite == nil ||
+		payload.Invite.Group == nil {
+		return nil
+	}
 
-		switch rsp.Content {
-		case t.ReceiveCall:
-			return fmt.Sprintf("%s%s", green.Render("Du wirst angerufen! Antworte innerhalb von 15sek:"),
-				blue.Render("\n		-> '/call accept' um anzunehmen"+
-					"\n		-> '/call deny' um abzulehnen"))
+	return payload.Invite
+}
+
+// lockMarker is put in front of the name of a protected group
+const lockMarker = "🔒"
+
+// groupName returns the name of the group with the lockMarker if it is protected
+func groupName(name string, protected bool) string {
+	if !protected {
+		return name
+	}
+
+	return fmt.Sprintf("%s %s", lockMarker, name)
+}
 
-		case t.CallAccepted:
-			m.userService.Client.HandleSignal(rsp, false, true)
-			return green.Render("Dein Anruf wurde angenommen, verbinde...")
+// markProtectedGroups replaces the protected column of the group list by the lockMarker
+// in front of the name, rows which can't be decoded are kept
+func markProtectedGroups(rows []json.RawMessage) []json.RawMessage {
+	marked := []json.RawMessage{}
 
-		case t.CallDenied:
-			m.userService.Client.DeletePeers(rsp.ClientId, false, false)
-			return green.Render("- Dein Anruf wurde abgelehnt -")
+	for _, row := range rows {
+		var group map[string]any
+		if json.Unmarshal(row, &group) != nil {
+			marked = append(marked, row)
+			continue
 		}
 
-		m.userService.Client.HandleSignal(rsp, false, false)
+		protected, _ := group["protected"].(bool)
+		name, _ := group["name"].(string)
+		group["name"] = groupName(name, protected)
+		delete(group, "protected")
+
+		data, err := json.Marshal(group)
+		if err != nil {
+			marked = append(marked, row)
+			continue
+		}
+
+		marked = append(marked, data)
+	}
 
+	return marked
+}
+
+// groupNames returns the names of the groups of a group list for the suggestions,
+// rows which can't be decoded are skipped
+func groupNames(rows []json.RawMessage) []string {
+	names := []string{}
+
+	for _, row := range rows {
+		var group struct {
+			Name string `json:"name"`
+		}
+
+		if json.Unmarshal(row, &group) == nil && group.Name != "" {
+			names = append(names, group.Name)
+		}
+	}
+
+	return names
+}
+
+// maxUnreadHistory is the maximum number of unread messages replayed when switching into a group
+// or the lobby
+const maxUnreadHistory = 200
+
+// inInactiveGroup reports whether the response belongs to one of the groups of the client
+// or the lobby (empty groupId) which isn't the active one, private messages and notices
+// without group are shown everywhere
+func (m *model) inInactiveGroup(rsp *t.Response) bool {
+	activeId := m.userService.Client.GetGroupId()
+
+	switch rsp.Kind {
+	case t.KindChat:
+		var chat t.ChatPayload
+		if err := rsp.DecodePayload(&chat); err == nil && chat.Private {
+			return false
+		}
+		return rsp.GroupId != activeId
+	case t.KindUserJoined, t.KindUserLeft:
+		return rsp.GroupId != activeId
+	case t.KindNotice:
+		return rsp.GroupId != "" && rsp.GroupId != activeId
+	}
+
+	return false
+}
+
+// evaluateInactiveGroup counts chat messages of an inactive group or the lobby as unread and
+// only shows the notices of the group, prefixed with its name
+func (m *model) evaluateInactiveGroup(rsp *t.Response) string {
+	if rsp.GroupId == "" {
+		if rsp.Kind == t.KindChat {
+			m.userService.Client.AddUnread("")
+			m.refreshTitle()
+		}
 		return ""
+	}
 
-	// slice output
-	case strings.HasPrefix(rsp.Content, "["):
+	group := m.userService.Client.GetGroup(rsp.GroupId)
+	if group == nil {
+		return ""
+	}
 
-		output, err := JSONToTable(rsp.Content)
-		if err != nil {
-			return red.Render(fmt.Sprintf("%v: error formatting json to table", err))
+	switch rsp.Kind {
+	case t.KindChat:
+		m.userService.Client.AddUnread(rsp.GroupId)
+		m.refreshTitle()
+
+	case t.KindNotice:
+		var notice t.NoticePayload
+		if err := rsp.DecodePayload(&notice); err != nil || notice.Text == "" {
+			return ""
 		}
 
-		return output
+		return fmt.Sprintf("%s %s", turkis.Faint(true).Render(fmt.Sprintf("[%s]", group.Name)), blue.Render(notice.Text))
 	}
 
-	// response output
-	rspString = fmt.Sprintf("%s: %s", turkis.Render(rsp.RspName), rsp.Content)
+	return ""
+}
+
+// refreshTitle renders the title of the active group or of the lobby
+func (m *model) refreshTitle() {
+	name := m.userService.Client.GetName()
+
+	group := m.userService.Client.GetGroup(m.userService.Client.GetGroupId())
+	if group == nil {
+		m.RenderTitle(t.RegisterFlag, []string{name})
+		return
+	}
+
+	m.RenderTitle(t.AddGroupFlag, []string{name, groupName(group.Name, group.Protected), group.Topic})
+}
+
+// refreshUsers requests the users of the current group or the lobby
+func (m *model) refreshUsers() {
+	if m.userService.Client.GetGroupId() != "" {
+		m.userService.Executor("/group users")
+		return
+	}
+
+	m.userService.Executor("/users")
+}
+
+// RenderHistory renders recorded messages like incoming ones, prefixed with the time they were sent
+func RenderHistory(messages []*t.JsonMessage) string {
+	if len(messages) == 0 {
+		return blue.Render("Noch keine Nachrichten")
+	}
+
+	lines := []string{}
+
+	for _, msg := range messages {
+		name := msg.Sender
+		if msg.Scope == t.PrivateScope {
+			name = fmt.Sprintf("[%s]", msg.Sender)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %s: %s",
+			faint.Render(msg.Time.Local().Format("02.01. 15:04")), turkis.Render(name), msg.Content))
+	}
 
-	return rspString
+	return purple.BorderStyle(lipgloss.NormalBorder()).BorderLeft(true).
+		BorderForeground(purple.GetForeground()).Render(strings.Join(lines, "\n"))
 }
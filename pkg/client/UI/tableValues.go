@@ -174,11 +174,17 @@ func (t *Table) ConvertClientsToRows(clientToBlink string) {
 	t.rows = []table.Row{}
 
 	for _, client := range t.clients {
+		// the role is only set in the users of the own group
+		groupName := client.GroupName
+		if client.Role != "" {
+			groupName = fmt.Sprintf("%s (%s)", client.GroupName, client.Role)
+		}
+
 		if client.ClientId == clientToBlink {
 			t.rows = append(t.rows, []string{
 				green.Render(client.Name),
 				green.Render(client.CallState),
-				green.Render(client.GroupName),
+				green.Render(groupName),
 				client.ClientId,
 				client.GroupId,
 			})
@@ -187,7 +193,7 @@ func (t *Table) ConvertClientsToRows(clientToBlink string) {
 		t.rows = append(t.rows, []string{
 			client.Name,
 			client.CallState,
-			client.GroupName,
+			groupName,
 			client.ClientId,
 			client.GroupId,
 		})
@@ -252,6 +258,19 @@ func (t *Table) ChangeSelectedStyle() {
 	t.focused = !t.focused
 }
 
+// Names returns the names of the clients in the table
+func (t *Table) Names() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	names := []string{}
+	for _, client := range t.clients {
+		names = append(names, client.Name)
+	}
+
+	return names
+}
+
 func (t *Table) GetClient(clientId string) *ty.JsonClient {
 	t.mu.RLock()
 	defer t.mu.RUnlock()
@@ -1,7 +1,9 @@
 package input
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"sync"
 
@@ -36,14 +38,8 @@ func NewUserService(c *n.Client) *UserService {
 	return u
 }
 
-func (u *UserService) HandleAddGroup(groupJson string) (*t.JsonGroup, error) {
-	group, err := t.DecodeStringToJsonGroup(groupJson)
-	if err != nil {
-		return nil, err
-	}
-
-	u.Client.SetGroupId(group.GroupId)
-	return group, nil
+func (u *UserService) HandleAddGroup(group *t.JsonGroup) {
+	u.Client.AddGroup(group)
 }
 
 func (u *UserService) InitializeSuggestions() []string {
@@ -56,13 +52,30 @@ func (u *UserService) InitializeSuggestions() []string {
 	return s
 }
 
+// Suggestions returns the commands completed with the names of the given users and groups
+func (u *UserService) Suggestions(users []string, groups []string) []string {
+	s := u.InitializeSuggestions()
+
+	for _, user := range users {
+		s = append(s, fmt.Sprintf("@%s ", user), fmt.Sprintf("/private %s ", user), fmt.Sprintf("/group invite %s", user))
+	}
+
+	for _, group := range groups {
+		s = append(s, fmt.Sprintf("/group join %s", group), fmt.Sprintf("/group switch %s", group))
+	}
+
+	slices.Sort(s)
+
+	return slices.Compact(s)
+}
+
 // ResponsePoller gets and displays messages if the client is not typing
 func (u *UserService) ResponsePoller() *t.Response {
 	var rsp *t.Response
 
 	rsp, ok := <-u.Client.Output
 	if !ok {
-		return &t.Response{Err: fmt.Sprintf("%v: channel is closed", t.ErrNoPermission)}
+		return t.ErrorEvent(fmt.Errorf("%w: channel is closed", t.ErrNoPermission))
 	}
 
 	return rsp
@@ -74,6 +87,11 @@ func (u *UserService) ParseInputToMessage(input string) *t.Message {
 
 	var plugin string
 
+	// @{name} {message} is short for /private {name} {message}
+	if name, ok := strings.CutPrefix(input, "@"); ok {
+		return u.Client.CreateMessage("", "/private", name, "")
+	}
+
 	ok := strings.HasPrefix(input, "/")
 	switch ok {
 	case true:
@@ -93,8 +111,19 @@ func (u *UserService) Executor(input string) {
 	msg := u.ParseInputToMessage(input)
 
 	err, comment := u.PlugReg.FindAndExecute(msg)
+
+	var rateErr *n.RateLimitError
+	if errors.As(err, &rateErr) {
+		u.Client.Output <- t.ErrorEvent(&t.Error{
+			Code:      t.CodeRateLimited,
+			Message:   fmt.Sprintf("Langsamer bitte! Du kannst in %s wieder senden", rateErr.RetryAfter),
+			Retryable: true,
+		})
+		return
+	}
+
 	if err != nil {
-		u.Client.Output <- &t.Response{Err: err.Error()}
+		u.Client.Output <- t.ErrorEvent(err)
 		return
 	}
 
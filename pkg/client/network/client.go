@@ -1,16 +1,26 @@
 package network
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	a "github.com/F4c3hugg3r/Go-Chat-Server/pkg/client/audio"
 	t "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+	"golang.org/x/net/websocket"
 )
 
+// BatchSize is the maximum number of responses requested per GET request
+const BatchSize = 50
+
 // Client handles all network tasks
 type Client struct {
 	clientName     string
@@ -19,6 +29,25 @@ type Client struct {
 	groupId        string
 	Registered     bool
 	CurrentCalling string
+	webSocket      bool
+	wsConn         *websocket.Conn
+	sessionFile    string
+	tlsConfig      *tls.Config
+	// every group the client is a member of and its unread messages, key: groupId,
+	// groupId is the active one
+	groups map[string]*t.JsonGroup
+	unread map[string]int
+	// the group of the current call, peer signals are sent to it
+	callGroupId string
+	// sequence number of the last received response, sent as ack cursor
+	lastSeq uint64
+	// the invitation which is currently prompted
+	invite *invitePrompt
+	// pending changes of the session file, it is written by the SessionSaver outside of mu
+	sessionDirty bool
+	dropSession  bool
+	saveSession  chan struct{}
+	saveMu       *sync.Mutex
 
 	mu                     *sync.RWMutex
 	cond                   *sync.Cond
@@ -37,13 +66,21 @@ type Client struct {
 	Peers map[string]*Peer
 }
 
-// NewClient generates a ChatClient and spawns a ResponseReceiver goroutine
+// invitePrompt is an invitation into a group waiting for an answer of the user
+type invitePrompt struct {
+	groupId  string
+	answered chan bool
+}
+
+// NewClient generates a ChatClient and spawns a ResponseReceiver, TokenRefresher and SessionSaver goroutine
 func NewClient(server string) *Client {
 	var err error
 	chatClient := &Client{
 		clientId:               t.GenerateSecureToken(32),
 		clientName:             "",
 		groupId:                "",
+		groups:                 make(map[string]*t.JsonGroup),
+		unread:                 make(map[string]int),
 		authToken:              "",
 		Output:                 make(chan *t.Response, 10000),
 		ClientChangeSignalChan: make(chan t.ClientsChangeSignal, 10000),
@@ -52,9 +89,11 @@ func NewClient(server string) *Client {
 		Registered:             false,
 		CurrentCalling:         "",
 
-		mu:      &sync.RWMutex{},
-		Url:     server,
-		LogChan: make(chan t.Log, 10000),
+		mu:          &sync.RWMutex{},
+		saveMu:      &sync.Mutex{},
+		saveSession: make(chan struct{}, 1),
+		Url:         server,
+		LogChan:     make(chan t.Log, 10000),
 
 		Peers: make(map[string]*Peer),
 	}
@@ -73,6 +112,8 @@ func NewClient(server string) *Client {
 	}
 
 	go chatClient.ResponseReceiver(server)
+	go chatClient.TokenRefresher()
+	go chatClient.SessionSaver()
 
 	return chatClient
 }
@@ -85,16 +126,20 @@ func (c *Client) RegisterEndpoints(url string) map[int]string {
 	endpoints[t.Delete] = fmt.Sprintf("%s/users/%s", url, c.clientId)
 	endpoints[t.Get] = fmt.Sprintf("%s/users/%s/chat", url, c.clientId)
 	endpoints[t.SignalWebRTC] = fmt.Sprintf("%s/users/%s/signal", url, c.clientId)
+	endpoints[t.WebSocket] = fmt.Sprintf("%s/users/%s/ws", webSocketUrl(url), c.clientId)
+	endpoints[t.Resume] = fmt.Sprintf("%s/users/%s/resume", url, c.clientId)
+	endpoints[t.RefreshToken] = fmt.Sprintf("%s/users/%s/token", url, c.clientId)
 
 	return endpoints
 }
 
-// Interrupt sends a Delete to the server and closes idle connections
+// Interrupt sends a Delete to the server, closes idle connections and saves pending session changes.
+// If the session is stored to be resumed, the client stays registered on the server instead
 func (c *Client) Interrupt() {
-	if c.Registered {
+	if c.Registered && !c.keepsSession() {
 		err := c.PostDelete(c.CreateMessage("", "/quit", "", ""))
 		if err != nil {
-			c.Output <- &t.Response{Err: fmt.Sprintf("%v: delete could not be sent", err)}
+			c.Output <- t.ErrorEvent(fmt.Errorf("%w: delete could not be sent", err))
 		}
 	}
 
@@ -102,6 +147,7 @@ func (c *Client) Interrupt() {
 	c.PortAudioMicInput.Stream.Close()
 
 	c.HttpClient.CloseIdleConnections()
+	c.SaveSession()
 }
 
 // DeletePeers deletes a Peer or all Peers out of the peers map
@@ -130,6 +176,7 @@ func (c *Client) DeletePeers(oppId string, wholeMap bool, sendToOppClient bool)
 		c.LogChan <- t.Log{Text: fmt.Sprintf("SignalingError für Peer %s gesendet", oppId)}
 
 		if len(c.Peers) < 1 {
+			c.setCallGroupId("")
 			c.ClientChangeSignalChan <- t.ClientsChangeSignal{CallState: t.NoCallFlag, OppId: c.GetClientId()}
 		}
 		return
@@ -152,11 +199,12 @@ func (c *Client) DeletePeers(oppId string, wholeMap bool, sendToOppClient bool)
 		c.LogChan <- t.Log{Text: fmt.Sprintf("SignalingError für Peer %s gesendet", id)}
 	}
 	c.LogChan <- t.Log{Text: "Alle Peers wurden gelöscht"}
+	c.setCallGroupId("")
 	c.ClientChangeSignalChan <- t.ClientsChangeSignal{CallState: t.NoCallFlag, OppId: c.GetClientId()}
 }
 
 func (c *Client) SendSignalingError(oppId string, ownId string, content string) {
-	msg := c.CreateMessage(ownId, fmt.Sprintf("/"+t.FailedConnectionFlag), content, oppId)
+	msg := c.createSignalMessage(ownId, fmt.Sprintf("/"+t.FailedConnectionFlag), content, oppId)
 	_, err := c.PostMessage(msg, t.SignalWebRTC)
 	if err != nil {
 		c.LogChan <- t.Log{Text: fmt.Sprintf("WebRTC: Fehler beim senden des ConnectionFailedFlags %v", err)}
@@ -164,18 +212,80 @@ func (c *Client) SendSignalingError(oppId string, ownId string, content string)
 }
 
 // ResponseReceiver gets responses if client is registered
-// and sends then into the output channel
+// and sends then into the output channel. The websocket transport is
+// preferred, if it can't be established long-polling is used instead.
+// If the server can't be reached the session is resumed as soon as it is back
 func (c *Client) ResponseReceiver(url string) {
 	for {
 		c.checkRegistered()
 
-		rsp, err := c.GetResponse(url)
-		if err != nil {
+		if c.useWebSocket() {
+			err := c.ReceiveWebSocket()
+			if err == nil {
+				continue
+			}
+
+			c.LogChan <- t.Log{Text: fmt.Sprintf("%v: falling back to long-polling", err), Method: "ResponseReceiver"}
+			c.setUseWebSocket(false)
+		}
+
+		rsps, err := c.GetResponses(url)
+
+		var rateErr *RateLimitError
+		switch {
+		case errors.As(err, &rateErr):
+			time.Sleep(rateErr.RetryAfter)
+			continue
+		case errors.Is(err, t.ErrChannelClosed), errors.Is(err, t.ErrNotAvailable):
+			c.reconnect()
+			continue
+		case err != nil:
+			continue
+		}
+
+		for _, rsp := range c.dedup(rsps...) {
+			c.Output <- rsp
+		}
+	}
+}
+
+// dedup drops responses which were already received and advances the cursor, which is saved
+// with the session. Responses without a sequence number come from older servers and are always kept
+func (c *Client) dedup(rsps ...*t.Response) []*t.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lastSeq := c.lastSeq
+
+	fresh := rsps[:0]
+	for _, rsp := range rsps {
+		if rsp.Seq == 0 {
+			fresh = append(fresh, rsp)
+			continue
+		}
+
+		if rsp.Seq <= c.lastSeq {
 			continue
 		}
 
-		c.Output <- rsp
+		c.lastSeq = rsp.Seq
+		fresh = append(fresh, rsp)
+	}
+
+	// the cursor changes with every batch, so it is only saved periodically
+	if c.lastSeq != lastSeq {
+		c.sessionDirty = true
 	}
+
+	return fresh
+}
+
+// getLastSeq returns the sequence number of the last received response
+func (c *Client) getLastSeq() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.lastSeq
 }
 
 // checkRegistered blocks until the client is being registered
@@ -196,6 +306,9 @@ func (c *Client) Register(rsp *t.Response) error {
 
 	c.clientName = rsp.RspName
 	c.authToken = rsp.Content
+	c.lastSeq = 0
+	c.webSocket = true
+	c.saveSessionRequireLock()
 
 	c.Registered = true
 	c.cond.Signal()
@@ -210,7 +323,10 @@ func (c *Client) Unregister() {
 
 	c.authToken = ""
 	c.clientName = ""
+	c.lastSeq = 0
 	c.Registered = false
+	c.closeWebSocketRequireLock()
+	c.deleteSessionRequireLock()
 }
 
 // GetAuthToken returns the authToken and a bool if the token is set
@@ -226,8 +342,17 @@ func (c *Client) GetAuthToken() (string, bool) {
 }
 
 // PostMessage marshals a Message and posts it the the given endpoint
-// returning the response and an error
+// returning the response and an error, error responses are returned as *t.Error
+// besides the response. WebRTC signals are sent over the
+// websocket connection if there is one, so no response is returned for them
 func (c *Client) PostMessage(msg *t.Message, endpoint int) (*t.Response, error) {
+	if endpoint == t.SignalWebRTC {
+		sent, err := c.SendWebSocket(msg)
+		if sent {
+			return nil, err
+		}
+	}
+
 	body, err := json.Marshal(&msg)
 	if err != nil {
 		return nil, fmt.Errorf("%w: error parsing json", err)
@@ -246,15 +371,19 @@ func (c *Client) PostMessage(msg *t.Message, endpoint int) (*t.Response, error)
 	}
 
 	if len(resBody) == 0 {
-		return nil, nil
+		return nil, responseError(res.StatusCode, nil, resBody)
 	}
 
 	rsp, err := t.DecodeToResponse(resBody)
-	if err != nil {
+	if err != nil && res.StatusCode < http.StatusBadRequest {
 		return nil, fmt.Errorf("%w: error decoding body to Response", err)
 	}
 
-	return rsp, nil
+	if err != nil {
+		return nil, responseError(res.StatusCode, nil, resBody)
+	}
+
+	return rsp, responseError(res.StatusCode, rsp, resBody)
 }
 
 // PostDelete sends a DELETE Request to the delete endpoint and
@@ -277,20 +406,33 @@ func (c *Client) PostDelete(msg *t.Message) error {
 	return nil
 }
 
-// getResponse sends a GET Request to the server, checks the Response
-// and returns the body
-func (c *Client) GetResponse(url string) (*t.Response, error) {
-	res, err := c.GetRequest(c.Endpoints[t.Get])
-	if err != nil {
-		c.Unregister()
+// GetResponses sends a GET Request to the server asking for a batch of up to
+// BatchSize responses, acknowledging every response up to the last received one.
+// It checks the Response and returns the decoded body.
+// Servers without batch support answer with a single response, which is
+// returned as a one element slice. t.ErrNotAvailable is returned if the server
+// can't be reached and t.ErrChannelClosed if it dropped the session
+func (c *Client) GetResponses(url string) ([]*t.Response, error) {
+	res, err := c.GetRequest(fmt.Sprintf("%s?batch=%d&ack=%d", c.Endpoints[t.Get], BatchSize, c.getLastSeq()))
+
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) {
+		return nil, err
+	}
 
-		return &t.Response{Err: fmt.Sprintf("%v: the connection to the server couldn't be established", err)},
-			fmt.Errorf("%w: server not available", err)
+	if err != nil {
+		return nil, fmt.Errorf("%w: server not available: %v", t.ErrNotAvailable, err)
 	}
 
 	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusRequestTimeout:
+		return nil, nil
+	case http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return nil, fmt.Errorf("%w: your session has expired, please register again", t.ErrChannelClosed)
+	default:
 		return nil, fmt.Errorf("%s: message couldn't be received", res.Status)
 	}
 
@@ -299,12 +441,12 @@ func (c *Client) GetResponse(url string) (*t.Response, error) {
 		return nil, fmt.Errorf("%s: message body couldn't be read", res.Status)
 	}
 
-	rsp, err := t.DecodeToResponse(body)
+	rsps, err := t.DecodeToResponses(body)
 	if err != nil {
-		return nil, fmt.Errorf("%s: error decoding body to Response", res.Status)
+		return nil, fmt.Errorf("%s: error decoding body to Responses", res.Status)
 	}
 
-	return rsp, nil
+	return rsps, nil
 }
 
 // CreateMessage creates a Message with the given parameters or
@@ -331,6 +473,18 @@ func (c *Client) CreateMessage(name string, plugin string, content string, clien
 	return msg
 }
 
+// createSignalMessage creates a Message like CreateMessage, which is sent to the group
+// of the current call instead of the active group
+func (c *Client) createSignalMessage(name string, plugin string, content string, clientId string) *t.Message {
+	msg := c.CreateMessage(name, plugin, content, clientId)
+
+	if callGroupId := c.CallGroupId(); callGroupId != "" {
+		msg.GroupId = callGroupId
+	}
+
+	return msg
+}
+
 func (c *Client) Mute(toMute string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -364,7 +518,7 @@ func (c *Client) AnswerCallInitialization(message *t.Message, answer string) err
 	}
 
 	c.LogChan <- t.Log{Text: fmt.Sprintf("Sende Antwort '%s' auf Initialisierungsanruf von %s", answer, c.GetCurrentCalling())}
-	msg := c.CreateMessage(message.ClientId, fmt.Sprintf("/"+t.InitializeSignalFlag), answer, c.GetCurrentCalling())
+	msg := c.createSignalMessage(message.ClientId, fmt.Sprintf("/"+t.InitializeSignalFlag), answer, c.GetCurrentCalling())
 
 	_, err := c.PostMessage(msg, t.SignalWebRTC)
 	if err != nil {
@@ -404,6 +558,9 @@ func (c *Client) HandlePeer(rsp *t.Response, initialSignal bool, accepted bool)
 		}
 
 		c.SetPeer(peer)
+		if rsp.GroupId != "" {
+			c.setCallGroupId(rsp.GroupId)
+		}
 		c.LogChan <- t.Log{Text: fmt.Sprintf("Peer mit id: %s angelegt", rsp.ClientId)}
 
 		if initialSignal {
@@ -463,18 +620,142 @@ func (c *Client) GetGroupId() string {
 	return c.groupId
 }
 
-func (c *Client) SetGroupId(id string) {
+// AddGroup adds the group to the groups of the client and makes it the active one
+func (c *Client) AddGroup(group *t.JsonGroup) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.groups[group.GroupId] = group
+	c.groupId = group.GroupId
+}
+
+// RemoveGroup removes the group from the groups of the client, the lobby becomes active
+// if it was the active group. An empty groupId removes the active group
+func (c *Client) RemoveGroup(groupId string) (wasActive bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.groupId = id
+	if groupId == "" {
+		groupId = c.groupId
+	}
+
+	delete(c.groups, groupId)
+	delete(c.unread, groupId)
+
+	if c.groupId != groupId {
+		return false
+	}
+
+	c.groupId = ""
+
+	return true
 }
 
-func (c *Client) UnsetGroupId() {
+// UpdateGroup replaces the group of the client by its changed version, nothing happens
+// if the client isn't a member
+func (c *Client) UpdateGroup(group *t.JsonGroup) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if _, member := c.groups[group.GroupId]; member {
+		c.groups[group.GroupId] = group
+	}
+}
+
+// SwitchGroup makes the group the active one, the lobby if group is nil, and returns
+// the number of messages received in it while it wasn't active
+func (c *Client) SwitchGroup(group *t.JsonGroup) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.groupId = ""
+	if group != nil {
+		if _, member := c.groups[group.GroupId]; !member {
+			c.groups[group.GroupId] = group
+		}
+
+		c.groupId = group.GroupId
+	}
+
+	unread := c.unread[c.groupId]
+	delete(c.unread, c.groupId)
+
+	return unread
+}
+
+// GetGroup returns the group of the client with the groupId or nil if it isn't a member
+func (c *Client) GetGroup(groupId string) *t.JsonGroup {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.groups[groupId]
+}
+
+// GetGroups returns every group the client is a member of sorted by name
+func (c *Client) GetGroups() []*t.JsonGroup {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	groups := slices.Collect(maps.Values(c.groups))
+	slices.SortFunc(groups, func(a, b *t.JsonGroup) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+
+	return groups
+}
+
+// setGroupsRequireLock replaces the groups of the client, the unread counters of
+// the lobby and the groups it is still a member of are kept
+func (c *Client) setGroupsRequireLock(groups []*t.JsonGroup, active *t.JsonGroup) {
+	c.groups = make(map[string]*t.JsonGroup)
+	for _, group := range groups {
+		c.groups[group.GroupId] = group
+	}
+
+	for groupId := range c.unread {
+		if _, member := c.groups[groupId]; !member && groupId != "" {
+			delete(c.unread, groupId)
+		}
+	}
+
 	c.groupId = ""
+	if active != nil {
+		c.groups[active.GroupId] = active
+		c.groupId = active.GroupId
+	}
+}
+
+// AddUnread counts a message received in the group or the lobby (empty groupId) while it isn't active
+func (c *Client) AddUnread(groupId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, member := c.groups[groupId]; member || groupId == "" {
+		c.unread[groupId]++
+	}
+}
+
+// Unread returns the number of messages received in the group or the lobby while it wasn't active
+func (c *Client) Unread(groupId string) int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.unread[groupId]
+}
+
+// CallGroupId returns the group of the current call, it is empty if there is no call
+func (c *Client) CallGroupId() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.callGroupId
+}
+
+func (c *Client) setCallGroupId(groupId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.callGroupId = groupId
 }
 
 // GetName returns the name of the client
@@ -485,6 +766,15 @@ func (c *Client) GetName() string {
 	return c.clientName
 }
 
+// Rename takes over the name the server renamed the client to and stores it in the session
+func (c *Client) Rename(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clientName = name
+	c.saveSessionRequireLock()
+}
+
 func (c *Client) GetCurrentCalling() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -499,6 +789,59 @@ func (c *Client) SetCurrentCalling(oppId string) {
 	c.CurrentCalling = oppId
 }
 
+// PromptInvite makes the invitation into the group the prompted one and returns the channel
+// receiving if it was accepted. The channel of a replaced prompt is closed
+func (c *Client) PromptInvite(groupId string) chan bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.invite != nil {
+		close(c.invite.answered)
+	}
+
+	c.invite = &invitePrompt{groupId: groupId, answered: make(chan bool, 1)}
+
+	return c.invite.answered
+}
+
+// GetCurrentInvite returns the groupId of the prompted invitation
+func (c *Client) GetCurrentInvite() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.invite == nil {
+		return ""
+	}
+
+	return c.invite.groupId
+}
+
+// AnswerInvite ends the prompt if the answered invitation is the prompted one,
+// an empty groupId answers the prompted invitation
+func (c *Client) AnswerInvite(groupId string, accepted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.invite == nil || groupId != "" && groupId != c.invite.groupId {
+		return
+	}
+
+	c.invite.answered <- accepted
+	c.invite = nil
+}
+
+// ExpireInvite ends the prompt if the invitation into the group is still the prompted one
+func (c *Client) ExpireInvite(groupId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.invite == nil || c.invite.groupId != groupId {
+		return
+	}
+
+	c.invite = nil
+}
+
 func (c *Client) DeletePeerSafely(oppId string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -4,8 +4,76 @@ import (
 	"bytes"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
+
+	t "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
 
+// RateLimitError is returned if the server rejected a request because too many were sent
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v: retry after %s", t.ErrRateLimited, e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return t.ErrRateLimited
+}
+
+// checkRateLimit closes the response and returns a RateLimitError if the server rejected the request
+// because of its rate limit
+func checkRateLimit(res *http.Response) (*http.Response, error) {
+	if res.StatusCode != http.StatusTooManyRequests {
+		return res, nil
+	}
+
+	res.Body.Close()
+
+	seconds, err := strconv.Atoi(res.Header.Get("Retry-After"))
+	if err != nil || seconds < 1 {
+		seconds = 1
+	}
+
+	return nil, &RateLimitError{RetryAfter: time.Duration(seconds) * time.Second}
+}
+
+// statusCodes maps the http status of error responses without an error object to a code
+var statusCodes = map[int]t.ErrorCode{
+	http.StatusBadRequest:      t.CodeParsing,
+	http.StatusForbidden:       t.CodeNoPermission,
+	http.StatusNotFound:        t.CodeNotAvailable,
+	http.StatusRequestTimeout:  t.CodeTimeout,
+	http.StatusGone:            t.CodeChannelClosed,
+	http.StatusTooManyRequests: t.CodeRateLimited,
+}
+
+// responseError turns an error response back into a *t.Error, so callers can check it with
+// errors.Is against the sentinel errors. Older servers only send an error string or a plain
+// text body, so their error is parsed from the string or the http status
+func responseError(status int, rsp *t.Response, body []byte) error {
+	if rsp != nil && rsp.Error != nil {
+		return rsp.Error
+	}
+
+	if status < http.StatusBadRequest {
+		return nil
+	}
+
+	if rsp != nil && rsp.Err != "" {
+		return t.ParseError(rsp.Err)
+	}
+
+	code, ok := statusCodes[status]
+	if !ok {
+		code = t.CodeInternal
+	}
+
+	return &t.Error{Code: code, Message: fmt.Sprintf("%d: %s", status, bytes.TrimSpace(body))}
+}
+
 // GetRequest sends a GET Request to the server including the authorization token
 func (c *Client) GetRequest(url string) (*http.Response, error) {
 	req, err := http.NewRequest("GET", url, nil)
@@ -25,7 +93,7 @@ func (c *Client) GetRequest(url string) (*http.Response, error) {
 		return nil, fmt.Errorf("%w: Fehler beim senden der GET request", err)
 	}
 
-	return res, nil
+	return checkRateLimit(res)
 }
 
 // DeleteRequest sends a DELETE Request to delete the client out of the server
@@ -49,7 +117,7 @@ func (c *Client) DeleteRequest(url string, body []byte) (*http.Response, error)
 		return nil, fmt.Errorf("%w: Fehler beim Absenden des Deletes", err)
 	}
 
-	return res, nil
+	return checkRateLimit(res)
 }
 
 // PostReqeust sends a Post Request to send a message to the server
@@ -73,5 +141,5 @@ func (c *Client) PostRequest(url string, body []byte) (*http.Response, error) {
 		return nil, fmt.Errorf("%w: Fehler beim Absenden der Nachricht", err)
 	}
 
-	return res, nil
+	return checkRateLimit(res)
 }
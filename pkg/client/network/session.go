@@ -0,0 +1,354 @@
+package network
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	t "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// bounds of the exponential backoff between reconnection attempts
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// interval in which the SessionSaver writes the cursor into the session file
+const sessionSaveInterval = 5 * time.Second
+
+// SetSessionFile sets the file the session is stored in, so it can be resumed after a restart
+func (c *Client) SetSessionFile(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sessionFile = path
+}
+
+// ResumeSession loads the session stored in the session file and tries to resume it.
+// If there is no stored session nothing happens
+func (c *Client) ResumeSession() error {
+	c.mu.Lock()
+
+	if c.sessionFile == "" {
+		c.mu.Unlock()
+		return nil
+	}
+
+	data, err := os.ReadFile(c.sessionFile)
+	if errors.Is(err, os.ErrNotExist) {
+		c.mu.Unlock()
+		return nil
+	}
+
+	if err != nil {
+		c.mu.Unlock()
+		return fmt.Errorf("%w: session file couldn't be read", err)
+	}
+
+	session := &t.JsonSession{}
+
+	err = json.Unmarshal(data, session)
+	if err != nil || session.ClientId == "" || session.AuthToken == "" {
+		c.deleteSessionRequireLock()
+		c.mu.Unlock()
+		return fmt.Errorf("%w: session file is corrupt", t.ErrParsing)
+	}
+
+	c.clientId = session.ClientId
+	c.authToken = session.AuthToken
+	c.lastSeq = session.LastSeq
+	c.Endpoints = c.RegisterEndpoints(c.Url)
+	c.mu.Unlock()
+
+	session, err = c.Resume()
+	if err != nil {
+		c.discardResumedIdentity(errors.Is(err, t.ErrChannelClosed))
+		return err
+	}
+
+	c.Output <- t.RegisteredEvent()
+
+	// the active group is joined last, so it stays active
+	for _, group := range session.Groups {
+		if session.Group == nil || group.GroupId != session.Group.GroupId {
+			c.Output <- t.GroupJoinedEvent(group)
+		}
+	}
+
+	switch {
+	case session.Group != nil:
+		c.Output <- t.GroupJoinedEvent(session.Group)
+	case len(session.Groups) > 0:
+		c.Output <- t.GroupSwitchedEvent(nil)
+	}
+
+	return nil
+}
+
+// Resume asks the server to resume the session of the client and registers the client
+// with the returned session. If the session has expired t.ErrChannelClosed is returned
+func (c *Client) Resume() (*t.JsonSession, error) {
+	res, err := c.PostRequest(c.Endpoints[t.Resume], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: server not available", err)
+	}
+
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusForbidden, http.StatusNotFound:
+		return nil, fmt.Errorf("%w: your session has expired, please register again", t.ErrChannelClosed)
+	default:
+		return nil, fmt.Errorf("%s: session couldn't be resumed", res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading response body", err)
+	}
+
+	session := &t.JsonSession{}
+
+	err = json.Unmarshal(body, session)
+	if err != nil {
+		return nil, fmt.Errorf("%w: error decoding body to session", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.clientName = session.Name
+	c.authToken = session.AuthToken
+	c.setGroupsRequireLock(session.Groups, session.Group)
+
+	c.webSocket = true
+	c.Registered = true
+	c.saveSessionRequireLock()
+	c.cond.Signal()
+
+	return session, nil
+}
+
+// reconnect tries to resume the session with an exponential backoff until it succeeds,
+// the client unregisters or the server reports that the session has expired
+func (c *Client) reconnect() {
+	backoff := minBackoff
+	notified := false
+
+	for c.isRegistered() {
+		_, err := c.Resume()
+		if err == nil {
+			if notified {
+				c.Output <- t.NoticeEvent("Verbindung zum Server wiederhergestellt")
+			}
+
+			return
+		}
+
+		if errors.Is(err, t.ErrChannelClosed) {
+			c.expireSession(err)
+			return
+		}
+
+		if !notified {
+			c.Output <- t.NoticeEvent("Verbindung zum Server verloren, versuche erneut zu verbinden...")
+			notified = true
+		}
+
+		var rateErr *RateLimitError
+		if errors.As(err, &rateErr) {
+			backoff = max(backoff, rateErr.RetryAfter)
+		}
+
+		c.LogChan <- t.Log{Text: fmt.Sprintf("%v: retrying in %v", err, backoff), Method: "reconnect"}
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// TokenRefresher refreshes the authToken of a registered client after two thirds of its
+// lifetime have passed, so it never expires while the client is running
+func (c *Client) TokenRefresher() {
+	for {
+		c.checkRegistered()
+
+		token, _ := c.GetAuthToken()
+
+		claims, err := t.ParseTokenClaims(token)
+		if err != nil {
+			// the server issues tokens without expiry
+			time.Sleep(time.Minute)
+			continue
+		}
+
+		refreshAt := time.Unix(claims.IssuedAt+(claims.ExpiresAt-claims.IssuedAt)*2/3, 0)
+		if wait := time.Until(refreshAt); wait > 0 {
+			// the token may be replaced in the meantime by a new registration or resumption
+			time.Sleep(min(wait, 30*time.Second))
+			continue
+		}
+
+		err = c.RefreshToken()
+		if err != nil {
+			c.LogChan <- t.Log{Text: fmt.Sprintf("%v: token couldn't be refreshed", err), Method: "TokenRefresher"}
+			time.Sleep(5 * time.Second)
+		}
+	}
+}
+
+// RefreshToken asks the server for a new authToken, the old one is revoked by the server
+func (c *Client) RefreshToken() error {
+	res, err := c.PostRequest(c.Endpoints[t.RefreshToken], nil)
+	if err != nil {
+		return fmt.Errorf("%w: server not available", err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: token couldn't be refreshed", res.Status)
+	}
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("%w: error reading response body", err)
+	}
+
+	rsp, err := t.DecodeToResponse(body)
+	if err != nil || rsp.Content == "" {
+		return fmt.Errorf("%w: error decoding body to Response", t.ErrParsing)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.authToken = rsp.Content
+	c.saveSessionRequireLock()
+
+	return nil
+}
+
+// expireSession unregisters the client after the server dropped its session
+func (c *Client) expireSession(err error) {
+	c.Unregister()
+
+	c.Output <- t.ErrorEvent(err)
+	c.Output <- t.UnregisteredEvent("")
+}
+
+// discardResumedIdentity goes back to a fresh clientId after a stored session couldn't be resumed,
+// the session file is only deleted if the session has expired
+func (c *Client) discardResumedIdentity(expired bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if expired {
+		c.deleteSessionRequireLock()
+	}
+
+	c.clientId = t.GenerateSecureToken(32)
+	c.authToken = ""
+	c.Endpoints = c.RegisterEndpoints(c.Url)
+}
+
+// keepsSession tells if the session is stored to be resumed after a restart
+func (c *Client) keepsSession() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.sessionFile != ""
+}
+
+func (c *Client) isRegistered() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.Registered
+}
+
+// SessionSaver writes changes of the session into the session file. Changes of the identity are
+// written immediately, the cursor is written periodically
+func (c *Client) SessionSaver() {
+	ticker := time.NewTicker(sessionSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.saveSession:
+		case <-ticker.C:
+		}
+
+		c.SaveSession()
+	}
+}
+
+// SaveSession writes or deletes the session file if the session changed since the last call.
+// The file is accessed outside of mu, so receiving responses is never blocked by it
+func (c *Client) SaveSession() {
+	c.saveMu.Lock()
+	defer c.saveMu.Unlock()
+
+	c.mu.Lock()
+	path, dirty, drop := c.sessionFile, c.sessionDirty, c.dropSession
+	session := &t.JsonSession{ClientId: c.clientId, Name: c.clientName, AuthToken: c.authToken, LastSeq: c.lastSeq}
+	c.sessionDirty, c.dropSession = false, false
+	c.mu.Unlock()
+
+	if path == "" {
+		return
+	}
+
+	if drop {
+		err := os.Remove(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.LogChan <- t.Log{Text: fmt.Sprintf("%v: session couldn't be deleted", err), Method: "SaveSession"}
+		}
+	}
+
+	if !dirty {
+		return
+	}
+
+	data, err := json.Marshal(session)
+	if err != nil {
+		return
+	}
+
+	err = os.WriteFile(path, data, 0o600)
+	if err != nil {
+		// retried by the next run unless the session is deleted in the meantime
+		c.mu.Lock()
+		c.sessionDirty = c.sessionDirty || !c.dropSession
+		c.mu.Unlock()
+
+		c.LogChan <- t.Log{Text: fmt.Sprintf("%v: session couldn't be saved", err), Method: "SaveSession"}
+	}
+}
+
+// saveSessionRequireLock marks the session as changed and wakes up the SessionSaver
+func (c *Client) saveSessionRequireLock() {
+	c.sessionDirty = true
+	c.dropSession = false
+
+	select {
+	case c.saveSession <- struct{}{}:
+	default:
+	}
+}
+
+// deleteSessionRequireLock marks the session file to be removed and wakes up the SessionSaver
+func (c *Client) deleteSessionRequireLock() {
+	c.sessionDirty = false
+	c.dropSession = true
+
+	select {
+	case c.saveSession <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,74 @@
+package network
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	t "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// ConfigureTLS makes the client trust the certificates of the caFile in addition to the system ones
+// and, if a pin is given, only accept a server certificate with that hex encoded sha256 fingerprint.
+// A pinned certificate doesn't need to be signed by a trusted CA, which allows self-signed certificates
+func (c *Client) ConfigureTLS(caFile string, pin string) error {
+	if caFile == "" && pin == "" {
+		return nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("%w: ca file couldn't be read", err)
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%w: ca file contains no certificates", t.ErrParsing)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if pin != "" {
+		fingerprint, err := hex.DecodeString(strings.ReplaceAll(pin, ":", ""))
+		if err != nil || len(fingerprint) != sha256.Size {
+			return fmt.Errorf("%w: pin has to be a hex encoded sha256 fingerprint", t.ErrParsing)
+		}
+
+		// the chain is only verified if a ca file is given, the pin is checked in any case
+		tlsConfig.InsecureSkipVerify = caFile == ""
+		tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return fmt.Errorf("%w: server sent no certificate", t.ErrNoPermission)
+			}
+
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], fingerprint) {
+				return fmt.Errorf("%w: server certificate doesn't match the pin", t.ErrNoPermission)
+			}
+
+			return nil
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tlsConfig = tlsConfig
+	c.HttpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment}
+
+	return nil
+}
@@ -181,7 +181,7 @@ func (p *Peer) OnICEConnectionStateChangeHandler(connectionState webrtc.ICEConne
 	if connectionState == webrtc.ICEConnectionStateConnected {
 		p.ClientsChangeSignalChan <- t.ClientsChangeSignal{CallState: t.ConnectedFlag, OppId: p.peerId}
 		p.ClientsChangeSignalChan <- t.ClientsChangeSignal{CallState: t.ConnectedFlag, OppId: p.ownId}
-		msg := p.chatClient.CreateMessage(p.ownId, fmt.Sprint("/", t.ConnectedFlag), "", p.peerId)
+		msg := p.chatClient.createSignalMessage(p.ownId, fmt.Sprint("/", t.ConnectedFlag), "", p.peerId)
 		_, err = p.chatClient.PostMessage(msg, t.SignalWebRTC)
 		if err != nil {
 			p.logChannel <- t.Log{Text: fmt.Sprintf("WebRTC: Fehler beim senden des Connected Flags %v", err)}
@@ -213,7 +213,7 @@ func (p *Peer) OnICECandidateHandler(candidate *webrtc.ICECandidate) {
 		return
 	}
 
-	candidateMsg := p.chatClient.CreateMessage(p.ownId, fmt.Sprint("/", t.ICECandidateFlag), candidate.ToJSON().Candidate, p.peerId)
+	candidateMsg := p.chatClient.createSignalMessage(p.ownId, fmt.Sprint("/", t.ICECandidateFlag), candidate.ToJSON().Candidate, p.peerId)
 	_, err := p.chatClient.PostMessage(candidateMsg, t.SignalWebRTC)
 	if err != nil {
 		p.logChannel <- t.Log{Text: fmt.Sprintf("WebRTC: Fehler beim Senden des ICE Candidates: %v", err)}
@@ -237,7 +237,7 @@ func (p *Peer) OnICECandidateHandler(candidate *webrtc.ICECandidate) {
 func (p *Peer) InitializeConnection() error {
 	p.SetSignalingState(t.OfferSignalFlag, false)
 
-	msg := p.chatClient.CreateMessage(p.ownId, fmt.Sprint("/", t.InitializeSignalFlag), "", p.peerId)
+	msg := p.chatClient.createSignalMessage(p.ownId, fmt.Sprint("/", t.InitializeSignalFlag), "", p.peerId)
 	_, err := p.chatClient.PostMessage(msg, t.SignalWebRTC)
 	if err != nil {
 		p.logChannel <- t.Log{Text: fmt.Sprintf("WebRTC: Fehler beim senden des Offers %v", err)}
@@ -269,7 +269,7 @@ func (p *Peer) OfferConnection() error {
 
 	p.logChannel <- t.Log{Text: "WebRTC: LocalDescription gesetzt, waiting for ICE gathering to start"}
 
-	msg := p.chatClient.CreateMessage(p.ownId, fmt.Sprint("/", t.OfferSignalFlag), p.peerConn.LocalDescription().SDP, p.peerId)
+	msg := p.chatClient.createSignalMessage(p.ownId, fmt.Sprint("/", t.OfferSignalFlag), p.peerConn.LocalDescription().SDP, p.peerId)
 	_, err = p.chatClient.PostMessage(msg, t.SignalWebRTC)
 	if err != nil {
 		p.logChannel <- t.Log{Text: fmt.Sprintf("WebRTC: Fehler beim senden des Offers %v", err)}
@@ -432,7 +432,7 @@ func (p *Peer) ReceiveOffer(rsp *t.Response) error {
 
 	p.logChannel <- t.Log{Text: "WebRTC: LocalDescription (Answer) gesetzt"}
 
-	msg := p.chatClient.CreateMessage(p.ownId, fmt.Sprint("/", t.AnswerSignalFlag), p.peerConn.LocalDescription().SDP, p.peerId)
+	msg := p.chatClient.createSignalMessage(p.ownId, fmt.Sprint("/", t.AnswerSignalFlag), p.peerConn.LocalDescription().SDP, p.peerId)
 	_, err = p.chatClient.PostMessage(msg, t.SignalWebRTC)
 	if err != nil {
 		p.logChannel <- t.Log{Text: fmt.Sprintf("WebRTC: Fehler beim Senden der Signal-Answer: %v", err)}
@@ -483,7 +483,7 @@ func (p *Peer) SetSignalingState(state string, sendToServer bool) error {
 	p.ClientsChangeSignalChan <- t.ClientsChangeSignal{CallState: state, OppId: p.peerId}
 
 	if sendToServer {
-		msg := p.chatClient.CreateMessage(p.ownId, fmt.Sprint("/", state), "", p.peerId)
+		msg := p.chatClient.createSignalMessage(p.ownId, fmt.Sprint("/", state), "", p.peerId)
 		_, err := p.chatClient.PostMessage(msg, t.SignalWebRTC)
 		if err != nil {
 			p.logChannel <- t.Log{Text: fmt.Sprintf("WebRTC: Fehler beim senden der %s: %v", state, err)}
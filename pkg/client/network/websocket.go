@@ -0,0 +1,122 @@
+package network
+
+import (
+	"fmt"
+	"strings"
+
+	t "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+	"golang.org/x/net/websocket"
+)
+
+// webSocketUrl converts the http(s) server url into the fitting ws(s) url
+func webSocketUrl(url string) string {
+	return strings.Replace(url, "http", "ws", 1)
+}
+
+// ReceiveWebSocket dials the websocket endpoint and writes every received response
+// into the output channel until the connection is closed
+func (c *Client) ReceiveWebSocket() error {
+	ws, err := c.DialWebSocket()
+	if err != nil {
+		return err
+	}
+
+	defer c.CloseWebSocket()
+
+	c.LogChan <- t.Log{Text: "websocket connection established", Method: "ReceiveWebSocket"}
+
+	for {
+		rsp := &t.Response{}
+
+		err = websocket.JSON.Receive(ws, rsp)
+		if err != nil {
+			c.LogChan <- t.Log{Text: fmt.Sprintf("%v: websocket connection closed", err), Method: "ReceiveWebSocket"}
+			return nil
+		}
+
+		for _, rsp := range c.dedup(rsp) {
+			c.Output <- rsp
+		}
+	}
+}
+
+// DialWebSocket opens the websocket connection with the authorization token,
+// responses after the last received one are redelivered by the server
+func (c *Client) DialWebSocket() (*websocket.Conn, error) {
+	authToken, ok := c.GetAuthToken()
+	if !ok {
+		return nil, fmt.Errorf("%w: client not registered anymore", t.ErrNoPermission)
+	}
+
+	config, err := websocket.NewConfig(fmt.Sprintf("%s?since=%d", c.Endpoints[t.WebSocket], c.getLastSeq()), c.Url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: websocket config couldn't be created", err)
+	}
+
+	config.Header.Set("Authorization", authToken)
+
+	c.mu.RLock()
+	config.TlsConfig = c.tlsConfig
+	c.mu.RUnlock()
+
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("%w: websocket couldn't be dialed", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.wsConn = ws
+
+	return ws, nil
+}
+
+// SendWebSocket sends a message over the websocket connection if there is one
+func (c *Client) SendWebSocket(msg *t.Message) (bool, error) {
+	c.mu.RLock()
+	ws := c.wsConn
+	c.mu.RUnlock()
+
+	if ws == nil {
+		return false, nil
+	}
+
+	err := websocket.JSON.Send(ws, msg)
+	if err != nil {
+		return true, fmt.Errorf("%w: message couldn't be sent over websocket", err)
+	}
+
+	return true, nil
+}
+
+// CloseWebSocket closes the websocket connection if there is one
+func (c *Client) CloseWebSocket() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closeWebSocketRequireLock()
+}
+
+func (c *Client) closeWebSocketRequireLock() {
+	if c.wsConn != nil {
+		c.wsConn.Close()
+		c.wsConn = nil
+	}
+}
+
+// useWebSocket tells if the websocket transport should be tried
+func (c *Client) useWebSocket() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.webSocket
+}
+
+// setUseWebSocket enables or disables the websocket transport
+func (c *Client) setUseWebSocket(use bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.webSocket = use
+}
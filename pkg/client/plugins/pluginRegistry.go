@@ -34,11 +34,15 @@ func RegisterPlugins(chatClient *n.Client) *PluginRegistry {
 	pr.Plugins["/time"] = NewTimePlugin(chatClient)
 	pr.Plugins["/users"] = NewUserPlugin(chatClient)
 	pr.Plugins["/register"] = NewRegisterClientPlugin(chatClient)
+	pr.Plugins["/signup"] = NewSignupPlugin(chatClient)
+	pr.Plugins["/login"] = NewLoginPlugin(chatClient)
+	pr.Plugins["/nick"] = NewNickPlugin(chatClient)
 	pr.Plugins["/broadcast"] = NewBroadcastPlugin(chatClient)
 	pr.Plugins["/quit"] = NewLogOutPlugin(chatClient)
 	pr.Plugins["/private"] = NewPrivateMessagePlugin(chatClient)
 	pr.Plugins["/group"] = NewGroupPlugin(chatClient)
 	pr.Plugins["/call"] = NewCallPlugin(chatClient)
+	pr.Plugins["/history"] = NewHistoryPlugin(chatClient)
 
 	pr.chatClient = chatClient
 
@@ -64,7 +64,7 @@ func (cp *CallPlugin) Execute(message *t.Message) (error, string) {
 
 	for _, oppClientId := range callableClientIds {
 		cp.c.LogChan <- t.Log{Text: fmt.Sprintf("Starting HandleSignal for client %s", oppClientId), Method: "CallPlugin.Execute"}
-		go cp.c.HandleSignal(&t.Response{ClientId: oppClientId}, true, false)
+		go cp.c.HandleSignal(&t.Response{ClientId: oppClientId, GroupId: rsp.GroupId}, true, false)
 	}
 
 	cp.c.LogChan <- t.Log{Text: "CallPlugin.Execute: Finished sending initialisation offers", Method: "CallPlugin.Execute"}
@@ -89,10 +89,31 @@ func (gp *GroupPlugin) Execute(message *t.Message) (error, string) {
 	// if strings.Contains(message.Content, t.LeaveGroupFlag) {
 	// 	gp.c.DeletePeersSafely(message.ClientId, true, true)
 	// }
+	fields := strings.Fields(message.Content)
+	if len(fields) > 0 && (fields[0] == "accept" || fields[0] == "decline") {
+		return gp.answerInvite(message, fields)
+	}
+
 	_, err := gp.c.PostMessage(message, t.PostPlugin)
 	return err, ""
 }
 
+// answerInvite answers the invitation into the given group or the prompted one
+// and ends the prompt if it was answered
+func (gp *GroupPlugin) answerInvite(message *t.Message, fields []string) (error, string) {
+	groupId := gp.c.GetCurrentInvite()
+	if len(fields) > 1 {
+		groupId = fields[1]
+	}
+
+	message.Content = strings.TrimSpace(fmt.Sprintf("%s %s", fields[0], groupId))
+
+	_, err := gp.c.PostMessage(message, t.PostPlugin)
+	gp.c.AnswerInvite(groupId, fields[0] == "accept")
+
+	return err, ""
+}
+
 // PrivateMessage Plugin lets a client send a private message to another client identified by it's clientId
 type PrivateMessagePlugin struct {
 	c *n.Client
@@ -156,9 +177,11 @@ func (rp *RegisterClientPlugin) CheckScope() int {
 }
 
 func (rp *RegisterClientPlugin) Execute(message *t.Message) (error, string) {
-	clientName := message.Content
-	if len(clientName) > 50 || len(clientName) < 3 {
-		return fmt.Errorf("%w: your name has to be between 3 and 50 chars long", t.ErrParsing), ""
+	clientName := strings.TrimSpace(message.Content)
+
+	err := t.CheckName(clientName)
+	if err != nil {
+		return err, ""
 	}
 
 	rsp, err := rp.c.PostMessage(rp.c.CreateMessage(clientName, message.Plugin, message.Content, message.ClientId), t.PostRegister)
@@ -174,6 +197,111 @@ func (rp *RegisterClientPlugin) Execute(message *t.Message) (error, string) {
 	return err, t.RegisterFlag
 }
 
+// SignupPlugin creates an account on the server and logs you in with it
+type SignupPlugin struct {
+	c *n.Client
+}
+
+func NewSignupPlugin(chatClient *n.Client) *SignupPlugin {
+	return &SignupPlugin{c: chatClient}
+}
+
+func (sp *SignupPlugin) CheckScope() int {
+	return UnregisteredOnly
+}
+
+func (sp *SignupPlugin) Execute(message *t.Message) (error, string) {
+	return postCredentials(sp.c, message)
+}
+
+// NickPlugin changes your name, the server makes sure nobody else is called like that
+type NickPlugin struct {
+	c *n.Client
+}
+
+func NewNickPlugin(chatClient *n.Client) *NickPlugin {
+	return &NickPlugin{c: chatClient}
+}
+
+func (np *NickPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (np *NickPlugin) Execute(message *t.Message) (error, string) {
+	err := t.CheckName(strings.TrimSpace(message.Content))
+	if err != nil {
+		return err, ""
+	}
+
+	rsp, err := np.c.PostMessage(message, t.PostPlugin)
+	if err != nil {
+		return err, ""
+	}
+
+	var user t.UserPayload
+	if rsp == nil || rsp.DecodePayload(&user) != nil || user.Name == "" {
+		return fmt.Errorf("%w: empty response from server", t.ErrNotAvailable), ""
+	}
+
+	// following messages have to claim the new name
+	np.c.Rename(user.Name)
+
+	return nil, ""
+}
+
+// LoginPlugin logs you in with an existing account
+type LoginPlugin struct {
+	c *n.Client
+}
+
+func NewLoginPlugin(chatClient *n.Client) *LoginPlugin {
+	return &LoginPlugin{c: chatClient}
+}
+
+func (lp *LoginPlugin) CheckScope() int {
+	return UnregisteredOnly
+}
+
+func (lp *LoginPlugin) Execute(message *t.Message) (error, string) {
+	return postCredentials(lp.c, message)
+}
+
+// postCredentials sends name and password to the register endpoint and registers
+// the client with the returned token
+func postCredentials(c *n.Client, message *t.Message) (error, string) {
+	fields := strings.Fields(message.Content)
+	if len(fields) != 2 {
+		return fmt.Errorf("%w: expected {name} {password}", t.ErrParsing), ""
+	}
+
+	clientName := fields[0]
+
+	err := t.CheckName(clientName)
+	if err != nil {
+		return err, ""
+	}
+
+	rsp, err := c.PostMessage(c.CreateMessage(clientName, message.Plugin, message.Content, message.ClientId), t.PostRegister)
+	if err != nil {
+		return fmt.Errorf("%w: error sending message", err), ""
+	}
+
+	if rsp == nil {
+		return fmt.Errorf("%w: empty response from server", t.ErrNotAvailable), ""
+	}
+
+	if rsp.Err != "" {
+		return t.ParseError(rsp.Err), ""
+	}
+
+	err = c.Register(rsp)
+	if err != nil {
+		return fmt.Errorf("%w: error registering client", err), ""
+	}
+
+	return err, t.RegisterFlag
+}
+
 // BroadcaastPlugin distributes an incomming message abroad all client channels if
 // a client can't receive, i'ts active status is set to false
 type BroadcastPlugin struct {
@@ -229,6 +357,24 @@ func (u *UserPlugin) Execute(message *t.Message) (error, string) {
 	return err, ""
 }
 
+// HistoryPlugin replays the newest lobby and private messages, only the lobby messages with --lobby
+type HistoryPlugin struct {
+	c *n.Client
+}
+
+func NewHistoryPlugin(chatClient *n.Client) *HistoryPlugin {
+	return &HistoryPlugin{c: chatClient}
+}
+
+func (hp *HistoryPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (hp *HistoryPlugin) Execute(message *t.Message) (error, string) {
+	_, err := hp.c.PostMessage(message, t.PostPlugin)
+	return err, ""
+}
+
 // TimePlugin tells you the current time
 type TimePlugin struct {
 	c *n.Client
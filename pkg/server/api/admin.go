@@ -0,0 +1,235 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// noticeRequest contains the text of a server notice
+type noticeRequest struct {
+	Content string `json:"content"`
+}
+
+// maxUsersRequest contains the new usercap
+type maxUsersRequest struct {
+	MaxUsers int `json:"maxUsers"`
+}
+
+// persistentRequest flags a group as persistent or ad-hoc group
+type persistentRequest struct {
+	Persistent *bool `json:"persistent"`
+}
+
+// revokeRequest names the token to revoke, if it is empty the current token
+// of the client with the clientId is revoked instead
+type revokeRequest struct {
+	Token    string `json:"token"`
+	ClientId string `json:"clientId"`
+}
+
+// AdminMiddleware checks if the Authorization header contains the admin key and
+// throws an error if not or if the admin api is disabled
+func (handler *ServerHandler) AdminMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adminKey := handler.Config.Get().AdminKey
+		if adminKey == "" {
+			writeError(w, fmt.Errorf("%w: admin api is disabled", ty.ErrNotAvailable))
+			return
+		}
+
+		if !handler.matchesAdminKey(r.Header.Get("Authorization")) {
+			writeError(w, fmt.Errorf("%w: admin key doesn't match", ty.ErrNoPermission))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// matchesAdminKey compares the key with the admin key in constant time, it never matches if the admin api is disabled
+func (handler *ServerHandler) matchesAdminKey(key string) bool {
+	adminKey := handler.Config.Get().AdminKey
+	if adminKey == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1
+}
+
+// HandleRevokeToken puts a token onto the revocation list, so it can't be used anymore
+// even though it isn't expired yet
+func (handler *ServerHandler) HandleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	request := revokeRequest{}
+
+	err := handler.decodeAdminRequest(w, r, &request)
+	if err != nil || (request.Token == "" && request.ClientId == "") {
+		writeError(w, fmt.Errorf("%w: request body has to contain a token or clientId", ty.ErrParsing))
+		return
+	}
+
+	err = handler.Service.RevokeToken(request.Token, request.ClientId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListClients lists every client with its last activity
+func (handler *ServerHandler) HandleListClients(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, handler.Service.Clients())
+}
+
+// HandleListGroups lists every group with its members and rtc pairs
+func (handler *ServerHandler) HandleListGroups(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, handler.Service.Groups())
+}
+
+// HandleDeliveryStats lists the queued, prioritized, dropped and evicted responses of every client
+func (handler *ServerHandler) HandleDeliveryStats(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, handler.Service.DeliveryStats())
+}
+
+// HandleKick logs out a client
+// should receive a Path Parameter with clientId in it
+func (handler *ServerHandler) HandleKick(w http.ResponseWriter, r *http.Request) {
+	_, err := handler.Service.Kick(r.PathValue("clientId"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleBan logs out a client and bans its name
+// should receive a Path Parameter with clientId in it
+func (handler *ServerHandler) HandleBan(w http.ResponseWriter, r *http.Request) {
+	err := handler.Service.Ban(r.PathValue("clientId"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleListBans lists every banned name
+func (handler *ServerHandler) HandleListBans(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, handler.Service.Bans())
+}
+
+// HandleUnban allows a banned name to register again
+// should receive a Path Parameter with name in it
+func (handler *ServerHandler) HandleUnban(w http.ResponseWriter, r *http.Request) {
+	err := handler.Service.Unban(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleDeleteGroup removes every member from a group and deletes it
+// should receive a Path Parameter with groupId in it
+func (handler *ServerHandler) HandleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	err := handler.Service.DeleteGroup(r.PathValue("groupId"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSetPersistent flags a group as persistent, so it is kept while empty and across restarts,
+// or as ad-hoc group. Should receive a Path Parameter with groupId in it
+func (handler *ServerHandler) HandleSetPersistent(w http.ResponseWriter, r *http.Request) {
+	request := persistentRequest{}
+
+	err := handler.decodeAdminRequest(w, r, &request)
+	if err != nil || request.Persistent == nil {
+		writeError(w, fmt.Errorf("%w: request body has to contain persistent", ty.ErrParsing))
+		return
+	}
+
+	err = handler.Service.SetGroupPersistent(r.PathValue("groupId"), *request.Persistent)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleNotice sends a server notice to every client
+func (handler *ServerHandler) HandleNotice(w http.ResponseWriter, r *http.Request) {
+	request := noticeRequest{}
+
+	err := handler.decodeAdminRequest(w, r, &request)
+	if err != nil || request.Content == "" {
+		writeError(w, fmt.Errorf("%w: request body has to contain a content", ty.ErrParsing))
+		return
+	}
+
+	handler.Service.Notice(request.Content)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleSetMaxUsers changes the usercap while the server is running, clients above the
+// new usercap stay registered
+func (handler *ServerHandler) HandleSetMaxUsers(w http.ResponseWriter, r *http.Request) {
+	request := maxUsersRequest{}
+
+	err := handler.decodeAdminRequest(w, r, &request)
+	if err != nil || request.MaxUsers < 1 {
+		writeError(w, fmt.Errorf("%w: request body has to contain a positive maxUsers", ty.ErrParsing))
+		return
+	}
+
+	handler.Config.Update(func(cfg *config.Config) {
+		cfg.MaxUsers = request.MaxUsers
+	})
+
+	fmt.Printf("\nmaxUsers set to %d", request.MaxUsers)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeAdminRequest reads the json request body into the request
+func (handler *ServerHandler) decodeAdminRequest(w http.ResponseWriter, r *http.Request, request any) error {
+	bodyMax := http.MaxBytesReader(w, r.Body, handler.Config.Get().MaxBodyBytes)
+	defer r.Body.Close()
+
+	body, err := io.ReadAll(bodyMax)
+	if err != nil {
+		return fmt.Errorf("%w: error reading request body", err)
+	}
+
+	return json.Unmarshal(body, request)
+}
+
+// writeJson writes the value as json response
+func writeJson(w http.ResponseWriter, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, "error formatting response to json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	_, err = w.Write(body)
+	if err != nil {
+		http.Error(w, "couldn't write response", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// statusOf maps the code of an error to its http status. Empty results aren't errors,
+// so ty.CodeNotAvailable only stands for missing resources
+func statusOf(code ty.ErrorCode) int {
+	switch code {
+	case ty.CodeNotAvailable:
+		return http.StatusNotFound
+	case ty.CodeNoPermission:
+		return http.StatusForbidden
+	case ty.CodeEmptyString, ty.CodeParsing:
+		return http.StatusBadRequest
+	case ty.CodeTimeout:
+		return http.StatusRequestTimeout
+	case ty.CodeChannelClosed:
+		return http.StatusGone
+	case ty.CodeRateLimited:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeError answers with the http status of the error and the error as json response
+func writeError(w http.ResponseWriter, err error) {
+	writeResponse(w, ty.ErrorEvent(err))
+}
+
+// writeResponse writes the response as json, error responses are answered with the http status
+// of their code and a Retry-After header if the error contains a retryAfter detail. Plugins
+// without response, like most call plugins, are answered with null
+func writeResponse(w http.ResponseWriter, rsp *ty.Response) {
+	body, err := json.Marshal(rsp)
+	if err != nil {
+		http.Error(w, "error formatting response to json", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if rsp != nil && rsp.Error != nil {
+		if retryAfter, ok := rsp.Error.Details["retryAfter"]; ok {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+
+		w.WriteHeader(statusOf(rsp.Error.Code))
+	}
+
+	_, err = w.Write(body)
+	if err != nil {
+		fmt.Printf("\n%v: couldn't write response", err)
+	}
+}
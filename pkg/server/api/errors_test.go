@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmptyResultsAreNoErrors(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	tests := []struct {
+		plugin  string
+		content string
+		status  int
+	}{
+		{"/group", "list", http.StatusOK},
+		{"/group", "invites", http.StatusOK},
+		{"/history", "", http.StatusOK},
+		// missing resources stay errors
+		{"/group", "accept", http.StatusNotFound},
+		{"/group", "join Nowhere", http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		res := run(t, server.URL, ClientId, token, test.plugin, test.content)
+		res.Body.Close()
+
+		assert.Equal(t, test.status, res.StatusCode, "%s %s", test.plugin, test.content)
+	}
+}
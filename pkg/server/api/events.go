@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// streamTimeout is the longest a stream waits for a response before it checks its token again
+const streamTimeout = 10 * time.Second
+
+// HandleEventStream streams every response of the client as server-sent events. The event name
+// is the Kind of the response and its id the sequence number. Responses up to the Last-Event-ID
+// header or since query parameter are acknowledged and unacknowledged ones after it are replayed first,
+// a stream without cursor starts at the current position. The stream ends as soon as its token is no longer valid
+// should receive a Path Parameter with clientId in it
+func (handler *ServerHandler) HandleEventStream(w http.ResponseWriter, r *http.Request) {
+	clientId := r.PathValue("clientId")
+	if clientId == "" {
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
+		return
+	}
+
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("since")
+	}
+
+	lastEventId, resumed, err := parseCursor(cursor)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	client, err := handler.Service.GetClient(clientId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+
+	// the write timeout of the http server would otherwise end the stream
+	err = controller.SetWriteDeadline(time.Time{})
+	if err != nil {
+		writeError(w, fmt.Errorf("%w: streaming not supported", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	token := r.Header.Get("Authorization")
+
+	var pending []*ty.Response
+	if resumed {
+		pending = redeliver(client, lastEventId)
+	}
+
+	for _, rsp := range pending {
+		err = writeEvent(w, rsp)
+		if err != nil {
+			return
+		}
+	}
+
+	for {
+		err = controller.Flush()
+		if err != nil {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), streamTimeout)
+		rsp, err := client.Receive(ctx)
+		cancel()
+
+		switch {
+		case r.Context().Err() != nil, errors.Is(err, ty.ErrChannelClosed):
+			return
+
+		case !handler.tokenValid(clientId, token):
+			// the unsent response stays unacknowledged and is redelivered to the next stream
+			return
+
+		case errors.Is(err, ty.ErrTimeoutReached):
+			_, err = io.WriteString(w, ": keep-alive\n\n")
+
+		default:
+			err = writeEvent(w, rsp)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes a response in the server-sent events format, the event is named after
+// the RspName like before responses had a kind, the kind only names responses without RspName
+func writeEvent(w io.Writer, rsp *ty.Response) error {
+	data, err := json.Marshal(rsp)
+	if err != nil {
+		return fmt.Errorf("%w: error formatting response to json", err)
+	}
+
+	name := rsp.RspName
+	if name == "" {
+		name = string(rsp.Kind)
+	}
+	if name == "" {
+		name = "message"
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", rsp.Seq, name, data)
+
+	return err
+}
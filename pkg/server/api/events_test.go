@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// nextEventData returns the data of the next event of the stream
+func nextEventData(t *testing.T, scanner *bufio.Scanner) string {
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			return data
+		}
+	}
+
+	t.Fatal("stream ended without event")
+	return ""
+}
+
+func TestEventStreamStartsAtCurrentPosition(t *testing.T) {
+	server, service := newTestServer(t, nil)
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	client, err := service.GetClient(ClientId)
+	assert.Nil(t, err)
+
+	// the notice is delivered but not acknowledged
+	assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent("old")))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	_, err = client.Receive(ctx)
+	cancel()
+	assert.Nil(t, err)
+
+	res := request(t, http.MethodGet, server.URL+"/users/"+ClientId+"/events", token, nil)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent("new")))
+	assert.Contains(t, nextEventData(t, bufio.NewScanner(res.Body)), `"new"`)
+	res.Body.Close()
+
+	res = request(t, http.MethodGet, server.URL+"/users/"+ClientId+"/events", token, http.Header{"Last-Event-Id": {"0"}})
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	assert.Contains(t, nextEventData(t, bufio.NewScanner(res.Body)), `"old"`)
+}
+
+func TestEventStreamEndsAfterTokenRefresh(t *testing.T) {
+	server, service := newTestServer(t, nil)
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	res := request(t, http.MethodGet, server.URL+"/users/"+ClientId+"/events", token, nil)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	refresh := request(t, http.MethodPost, server.URL+"/users/"+ClientId+"/token", token, nil)
+	refresh.Body.Close()
+	assert.Equal(t, http.StatusOK, refresh.StatusCode)
+
+	// the stream notices the replaced token with the next response and doesn't send it
+	assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent("after refresh")))
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		assert.NotContains(t, scanner.Text(), "after refresh")
+	}
+}
+
+func TestEventNames(t *testing.T) {
+	tests := []struct {
+		rsp  *ty.Response
+		name string
+	}{
+		{ty.UsersEvent(nil), ty.UsersFlag},
+		{ty.GroupJoinedEvent(&ty.JsonGroup{Name: "Room"}), ty.AddGroupFlag},
+		{ty.SignalEvent(ty.OfferSignalFlag, ClientId, "sdp"), ty.OfferSignalFlag},
+		{ty.SignalEvent(ty.ICECandidateFlag, ClientId, "candidate"), ty.ICECandidateFlag},
+		{ty.NoticeEvent("text"), string(ty.KindNotice)},
+		{&ty.Response{Content: "legacy"}, "message"},
+	}
+
+	for _, test := range tests {
+		var event strings.Builder
+		assert.Nil(t, writeEvent(&event, test.rsp))
+		assert.Contains(t, event.String(), "\nevent: "+test.name+"\n")
+	}
+}
@@ -4,11 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	chat "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/chat"
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
 
@@ -16,54 +19,87 @@ type ServerHandler struct {
 	Service *chat.ChatService
 	Plugins *chat.PluginRegistry
 	WebRTC  *chat.WebRTCRegistry
+	Config  *config.Holder
+	Limiter *RateLimiter
 }
 
-func NewServerHandler(chatService *chat.ChatService, pluginReg *chat.PluginRegistry, webRTCRegistry *chat.WebRTCRegistry) *ServerHandler {
+func NewServerHandler(cfg *config.Holder, chatService *chat.ChatService, pluginReg *chat.PluginRegistry, webRTCRegistry *chat.WebRTCRegistry) *ServerHandler {
 	return &ServerHandler{
 		Service: chatService,
 		Plugins: pluginReg,
 		WebRTC:  webRTCRegistry,
+		Config:  cfg,
+		Limiter: NewRateLimiter(cfg),
 	}
 }
 
-// handleGetRequest displays a response when received and times out after 10s
-// if nothing is being send
+// handleGetRequest displays a response when received and times out after the configured
+// pollTimeout if nothing is being send
 // should receive a Path Parameter with clientId in it
+// if the query parameter batch is set, every queued response (up to the batch size
+// and MaxBatchSize) is returned as a json array instead of a single response
+// if the query parameter ack is set, every response up to this sequence number is acknowledged
+// and unacknowledged responses after it are redelivered before waiting for new ones
 func (handler *ServerHandler) HandleGetRequest(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(r.Context(), time.Second*10)
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(handler.Config.Get().PollTimeout))
 	defer cancel()
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "only GET Requests allowed", http.StatusBadRequest)
+		writeError(w, fmt.Errorf("%w: only GET Requests allowed", ty.ErrParsing))
 		return
 	}
 
 	clientId := r.PathValue("clientId")
 	if clientId == "" {
-		http.Error(w, "missing path parameter clientId", http.StatusBadRequest)
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
 		return
 	}
 
-	client, err := handler.Service.GetClient(clientId)
+	batchSize, err := handler.parseBatchSize(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ack, acknowledged, err := parseCursor(r.URL.Query().Get("ack"))
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, err)
 		return
 	}
 
-	rsp, err := client.Receive(ctx)
-	if errors.Is(err, ty.ErrChannelClosed) {
-		w.WriteHeader(http.StatusGone)
+	client, err := handler.Service.GetClient(clientId)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	if errors.Is(err, ty.ErrTimeoutReached) {
-		w.WriteHeader(http.StatusRequestTimeout)
+	var pending []*ty.Response
+	if acknowledged {
+		pending = redeliver(client, ack)
+	}
+
+	var rsp any
+
+	switch {
+	case len(pending) > 0 && batchSize == 0:
+		rsp = pending[0]
+	case len(pending) > 0:
+		rsp = pending[:min(len(pending), batchSize)]
+	case batchSize == 0:
+		rsp, err = client.Receive(ctx)
+	default:
+		rsp, err = client.ReceiveBatch(ctx, batchSize)
+	}
+
+	// a closed channel is answered with http.StatusGone and a timeout with http.StatusRequestTimeout
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
 	json, err := json.Marshal(rsp)
 	if err != nil {
-		http.Error(w, "error formatting response to json", http.StatusInternalServerError)
+		writeError(w, fmt.Errorf("%w: error formatting response to json", err))
 		return
 	}
 
@@ -73,38 +109,97 @@ func (handler *ServerHandler) HandleGetRequest(w http.ResponseWriter, r *http.Re
 	}
 }
 
+// parseBatchSize reads the batch query parameter and caps it at the configured maxBatch,
+// 0 means that batching was not requested
+func (handler *ServerHandler) parseBatchSize(r *http.Request) (int, error) {
+	param := r.URL.Query().Get("batch")
+	if param == "" {
+		return 0, nil
+	}
+
+	batchSize, err := strconv.Atoi(param)
+	if err != nil || batchSize < 1 {
+		return 0, fmt.Errorf("%w: batch has to be a positive number", ty.ErrParsing)
+	}
+
+	return min(batchSize, handler.Config.Get().MaxBatch), nil
+}
+
+// parseCursor parses the sequence number of an ack or since cursor, set is false if it is empty
+func parseCursor(value string) (uint64, bool, error) {
+	if value == "" {
+		return 0, false, nil
+	}
+
+	seq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%w: the cursor has to be a sequence number", ty.ErrParsing)
+	}
+
+	return seq, true, nil
+}
+
+// redeliver acknowledges every response of the client up to the cursor and returns
+// the delivered responses after it, which weren't acknowledged yet
+func redeliver(client *chat.Client, cursor uint64) []*ty.Response {
+	client.Ack(cursor)
+	return client.Unacked(cursor)
+}
+
 func (handler *ServerHandler) HandleRegistry(w http.ResponseWriter, r *http.Request) {
 	clientId := r.PathValue("clientId")
 	if clientId == "" {
-		http.Error(w, "missing path parameter clientId", http.StatusBadRequest)
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
 		return
 	}
 
-	bodyMax := http.MaxBytesReader(w, r.Body, 1<<20)
+	bodyMax := http.MaxBytesReader(w, r.Body, handler.Config.Get().MaxBodyBytes)
 	defer r.Body.Close()
 
 	body, err := io.ReadAll(bodyMax)
 
 	if err != nil {
-		http.Error(w, "error reading request body", http.StatusInternalServerError)
+		writeError(w, fmt.Errorf("%w: error reading request body", ty.ErrParsing))
 		return
 	}
 
 	message, err := ty.DecodeToMessage(body)
 	if err != nil {
-		http.Error(w, "error decoding request body", http.StatusInternalServerError)
+		writeError(w, fmt.Errorf("%w: error decoding request body", ty.ErrParsing))
+		return
+	}
+
+	// unregistered callers are only identified by the path
+	ctx := chat.WithCaller(r.Context(), chat.Caller{ClientId: clientId})
+
+	rsp, err := handler.Plugins.FindAndExecute(ctx, &message)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeResponse(w, rsp)
+}
+
+// HandleResume resumes the session of a disconnected client and returns it as json
+// should receive a Path Parameter with clientId in it
+func (handler *ServerHandler) HandleResume(w http.ResponseWriter, r *http.Request) {
+	clientId := r.PathValue("clientId")
+	if clientId == "" {
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
 		return
 	}
 
-	rsp, err := handler.Plugins.FindAndExecute(&message)
+	session, err := handler.Service.Resume(clientId)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, err)
 		return
 	}
 
-	body, err = json.Marshal(rsp)
+	body, err := json.Marshal(session)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, fmt.Errorf("%w: error formatting session to json", err))
+		return
 	}
 
 	_, err = w.Write(body)
@@ -113,52 +208,87 @@ func (handler *ServerHandler) HandleRegistry(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// HandleRefreshToken issues a new token for the client and revokes the old one, the response
+// has the same format as the registration response
+// should receive a Path Parameter with clientId in it
+func (handler *ServerHandler) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	clientId := r.PathValue("clientId")
+	if clientId == "" {
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
+		return
+	}
+
+	rsp, err := handler.Service.RefreshToken(clientId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	writeResponse(w, rsp)
+}
+
 func (handler *ServerHandler) HandleSignals(w http.ResponseWriter, r *http.Request) {
 	// SignalPlugin forwards webRTC signals (SDP, ICE Candidates) to the other group members
 	clientId := r.PathValue("clientId")
 	if clientId == "" {
-		http.Error(w, "missing path parameter clientId", http.StatusBadRequest)
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
 		return
 	}
 
-	bodyMax := http.MaxBytesReader(w, r.Body, 1<<20)
+	bodyMax := http.MaxBytesReader(w, r.Body, handler.Config.Get().MaxBodyBytes)
 	defer r.Body.Close()
 
 	body, err := io.ReadAll(bodyMax)
 
 	if err != nil {
-		http.Error(w, "error reading request body", http.StatusInternalServerError)
+		writeError(w, fmt.Errorf("%w: error reading request body", ty.ErrParsing))
 		return
 	}
 
 	message, err := ty.DecodeToMessage(body)
 	if err != nil {
-		http.Error(w, "error decoding request body", http.StatusInternalServerError)
+		writeError(w, fmt.Errorf("%w: error decoding request body", ty.ErrParsing))
 		return
 	}
 
 	client, err := handler.Service.GetClient(clientId)
 	if err != nil {
-		http.Error(w, "client not found ", http.StatusNotFound)
+		writeError(w, err)
 		return
 	}
 
-	rsp, err := client.Execute(handler.WebRTC, &message)
-	if err != nil {
-		handler.Service.Echo(message.Name, &ty.Response{ClientId: message.ClientId, RspName: ty.FailedConnectionFlag, Content: err.Error()})
-		handler.Service.Echo(message.ClientId, &ty.Response{ClientId: message.Name, RspName: ty.FailedConnectionFlag, Content: err.Error()})
+	rsp, err := client.Execute(handler.callerContext(r, client), handler.WebRTC, &message)
+	if isPermissionError(err) {
+		writeError(w, err)
 		return
 	}
 
-	body, err = json.Marshal(rsp)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		handler.echoSignalError(clientId, &message, err)
+		return
 	}
 
-	_, err = w.Write(body)
-	if err != nil {
-		http.Error(w, "couldn't write response", http.StatusInternalServerError)
-	}
+	writeResponse(w, rsp)
+}
+
+// echoSignalError informs both call participants that the signal couldn't be processed
+func (handler *ServerHandler) echoSignalError(ownId string, message *ty.Message, err error) {
+	handler.Service.Echo(ownId, ty.SignalEvent(ty.FailedConnectionFlag, message.ClientId, err.Error()))
+	handler.Service.Echo(message.ClientId, ty.SignalEvent(ty.FailedConnectionFlag, ownId, err.Error()))
+}
+
+// callerContext returns the request context carrying the authenticated client as caller
+func (handler *ServerHandler) callerContext(r *http.Request, client *chat.Client) context.Context {
+	return chat.WithCaller(r.Context(), client.Caller())
+}
+
+// isPermissionError reports whether the message claimed another sender than the verified caller
+// or the caller doesn't fulfill the scope of the plugin
+func isPermissionError(err error) bool {
+	var identityErr *chat.IdentityError
+	var scopeErr *chat.ScopeError
+
+	return errors.As(err, &identityErr) || errors.As(err, &scopeErr)
 }
 
 // handleMessages takes an incoming POST request with a message in i'ts body and distributes it to all clients
@@ -167,72 +297,75 @@ func (handler *ServerHandler) HandleSignals(w http.ResponseWriter, r *http.Reque
 func (handler *ServerHandler) HandleMessages(w http.ResponseWriter, r *http.Request) {
 	clientId := r.PathValue("clientId")
 	if clientId == "" {
-		http.Error(w, "missing path parameter clientId", http.StatusBadRequest)
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
 		return
 	}
 
-	bodyMax := http.MaxBytesReader(w, r.Body, 1<<20)
+	bodyMax := http.MaxBytesReader(w, r.Body, handler.Config.Get().MaxBodyBytes)
 	defer r.Body.Close()
 
 	body, err := io.ReadAll(bodyMax)
 
 	if err != nil {
-		http.Error(w, "error reading request body", http.StatusInternalServerError)
+		writeError(w, fmt.Errorf("%w: error reading request body", ty.ErrParsing))
 		return
 	}
 
 	message, err := ty.DecodeToMessage(body)
 	if err != nil {
-		http.Error(w, "error decoding request body", http.StatusInternalServerError)
+		writeError(w, fmt.Errorf("%w: error decoding request body", ty.ErrParsing))
 		return
 	}
 
 	client, err := handler.Service.GetClient(clientId)
 	if err != nil {
-		http.Error(w, "client not found ", http.StatusNotFound)
+		writeError(w, err)
 		return
 	}
 
-	rsp, err := client.Execute(handler.Plugins, &message)
+	rsp, err := client.Execute(handler.callerContext(r, client), handler.Plugins, &message)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, err)
 		return
 	}
 
-	err = handler.Service.Echo(clientId, rsp)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusRequestTimeout)
-	}
-
-	body, err = json.Marshal(rsp)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// errors are only answered to the request, so they aren't displayed twice
+	if rsp.Error == nil {
+		err = handler.Service.Echo(clientId, rsp)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
 	}
 
-	_, err = w.Write(body)
-	if err != nil {
-		http.Error(w, "couldn't write response", http.StatusInternalServerError)
-	}
+	writeResponse(w, rsp)
 }
 
-// authMiddleware checks if the authToken is fitting the token given while registry and throws
-// an error if not
+// authMiddleware checks if the authToken is validly signed, not expired or revoked and fitting
+// the current token of the client and throws an error if not
 func (handler *ServerHandler) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		clientId := r.PathValue("clientId")
 
 		token := r.Header.Get("Authorization")
 		if token == "" || clientId == "" {
-			http.Error(w, "missing path parameter clientId or authToken", http.StatusBadRequest)
+			writeError(w, fmt.Errorf("%w: missing path parameter clientId or authToken", ty.ErrEmptyString))
 			return
 		}
 
-		client, err := handler.Service.GetClient(clientId)
-		if err != nil || token != client.GetAuthToken() {
-			http.Error(w, "client does not exist or token doesn't match", http.StatusForbidden)
+		_, err := handler.Service.Authenticate(clientId, token)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: client does not exist or token doesn't match", ty.ErrNoPermission))
 			return
 		}
 
 		next(w, r)
 	}
 }
+
+// tokenValid reports if the token a stream was opened with still authenticates the client,
+// so streams end once it is revoked, expired or replaced by a refresh
+func (handler *ServerHandler) tokenValid(clientId, token string) bool {
+	_, err := handler.Service.Authenticate(clientId, token)
+	return err == nil
+}
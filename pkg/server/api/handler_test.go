@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestSignalWithoutResponse(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	res := run(t, server.URL, ClientId, token, "/group", "create Room")
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the call plugins answer successful signals without response
+	res = signal(t, server.URL, ClientId, token, ty.Message{Name: ClientId, Plugin: "/" + ty.FailedConnectionFlag})
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "null", string(body))
+}
+
+// poll receives a batch of responses, which acknowledges the responses up to ack
+func poll(t *testing.T, url string, clientId string, token string, ack uint64) []*ty.Response {
+	res := request(t, http.MethodGet, fmt.Sprintf("%s/users/%s/chat?batch=10&ack=%d", url, clientId, ack), token, nil)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	rsps := []*ty.Response{}
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(&rsps))
+
+	return rsps
+}
+
+func TestResumeRedeliversBacklog(t *testing.T) {
+	server, service := newTestServer(t, nil)
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent("first")))
+	assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent("second")))
+
+	// the responses are received, but the client loses them before acknowledging
+	assert.Len(t, poll(t, server.URL, ClientId, token, 0), 2)
+
+	res := request(t, http.MethodPost, server.URL+"/users/"+ClientId+"/resume", token, nil)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	session := &ty.JsonSession{}
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(session))
+	assert.Equal(t, ClientName, session.Name)
+
+	rsps := poll(t, server.URL, ClientId, token, 0)
+	if assert.Len(t, rsps, 2) {
+		assert.Equal(t, "first", rsps[0].Content)
+		assert.Equal(t, "second", rsps[1].Content)
+
+		// acknowledged responses aren't redelivered again
+		rsps = poll(t, server.URL, ClientId, token, rsps[0].Seq)
+		if assert.Len(t, rsps, 1) {
+			assert.Equal(t, "second", rsps[0].Content)
+		}
+	}
+}
+
+func TestPollingBatches(t *testing.T) {
+	server, service := newTestServer(t, func(cfg *config.Config) {
+		cfg.MaxBatch = 2
+	})
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	for _, content := range []string{"1", "2", "3"} {
+		assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent(content)))
+	}
+
+	// without batch a single response is returned
+	res := request(t, http.MethodGet, server.URL+"/users/"+ClientId+"/chat", token, nil)
+	single := &ty.Response{}
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(single))
+	res.Body.Close()
+	assert.Equal(t, "1", single.Content)
+
+	// the batch is capped at maxBatch
+	rsps := poll(t, server.URL, ClientId, token, single.Seq)
+	if assert.Len(t, rsps, 2) {
+		assert.Equal(t, "2", rsps[0].Content)
+		assert.Equal(t, "3", rsps[1].Content)
+	}
+
+	// redelivered responses are returned one by one without batch
+	res = request(t, http.MethodGet, fmt.Sprintf("%s/users/%s/chat?ack=%d", server.URL, ClientId, single.Seq), token, nil)
+	single = &ty.Response{}
+	assert.Nil(t, json.NewDecoder(res.Body).Decode(single))
+	res.Body.Close()
+	assert.Equal(t, "2", single.Content)
+
+	for _, query := range []string{"batch=0", "batch=many", "ack=-1"} {
+		res = request(t, http.MethodGet, server.URL+"/users/"+ClientId+"/chat?"+query, token, nil)
+		res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+}
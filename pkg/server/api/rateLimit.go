@@ -0,0 +1,215 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// Budget is a class of requests sharing a rate limit
+type Budget int
+
+const (
+	RegisterBudget Budget = iota
+	ChatBudget
+	CommandBudget
+	SignalBudget
+	// StreamBudget limits opening streams, resuming sessions and refreshing tokens
+	StreamBudget
+	// PollBudget limits polling the chat endpoint
+	PollBudget
+	// AdminBudget limits admin requests per ip
+	AdminBudget
+	// MessageBudget is resolved into ChatBudget or CommandBudget by the plugin of the message
+	MessageBudget
+)
+
+// buckets which weren't used for this time are full anyway and can be removed
+const bucketIdleTime = 10 * time.Minute
+
+// bucket is a token bucket which is refilled continuously with the rate of its budget
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter keeps a token bucket per budget and client or ip
+type RateLimiter struct {
+	config  *config.Holder
+	buckets map[string]*bucket
+	pruned  time.Time
+	mu      sync.Mutex
+}
+
+func NewRateLimiter(cfg *config.Holder) *RateLimiter {
+	return &RateLimiter{config: cfg, buckets: make(map[string]*bucket), pruned: time.Now()}
+}
+
+// limits returns the rate per second and the burst of the budget
+func (b Budget) limits(cfg *config.Config) (float64, int) {
+	switch b {
+	case RegisterBudget:
+		return cfg.RegisterRate, cfg.RegisterBurst
+	case ChatBudget:
+		return cfg.ChatRate, cfg.ChatBurst
+	case SignalBudget:
+		return cfg.SignalRate, cfg.SignalBurst
+	case StreamBudget:
+		return cfg.StreamRate, cfg.StreamBurst
+	case PollBudget:
+		return cfg.PollRate, cfg.PollBurst
+	case AdminBudget:
+		return cfg.AdminRate, cfg.AdminBurst
+	default:
+		return cfg.CommandRate, cfg.CommandBurst
+	}
+}
+
+// Allow takes a token out of the bucket of the budget and key, if the bucket is empty
+// false and the time until the next token is returned
+func (rl *RateLimiter) Allow(budget Budget, key string) (bool, time.Duration) {
+	rate, burst := budget.limits(rl.config.Get())
+	if rate <= 0 {
+		return true, 0
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := time.Now()
+	rl.pruneRequireLock(now)
+
+	bucketKey := fmt.Sprintf("%d:%s", budget, key)
+	b, exists := rl.buckets[bucketKey]
+	if !exists {
+		b = &bucket{tokens: float64(burst), last: now}
+		rl.buckets[bucketKey] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+
+	b.tokens--
+
+	return true, 0
+}
+
+// pruneRequireLock removes idle buckets at most once per bucketIdleTime
+func (rl *RateLimiter) pruneRequireLock(now time.Time) {
+	if now.Sub(rl.pruned) < bucketIdleTime {
+		return
+	}
+
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) > bucketIdleTime {
+			delete(rl.buckets, key)
+		}
+	}
+
+	rl.pruned = now
+}
+
+// RateLimitMiddleware rejects requests with http.StatusTooManyRequests and a Retry-After header
+// if the budget is used up. Registrations and admin requests are limited per ip, every other budget
+// per clientId, so it has to be wrapped by the AuthMiddleware
+func (handler *ServerHandler) RateLimitMiddleware(budget Budget, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("clientId")
+		if budget == RegisterBudget || budget == AdminBudget {
+			key = remoteIp(r)
+		}
+
+		requestBudget := budget
+		if budget == MessageBudget {
+			message, err := peekMessage(w, r, handler.Config.Get().MaxBodyBytes)
+			if err != nil {
+				writeError(w, fmt.Errorf("%w: error reading request body", ty.ErrParsing))
+				return
+			}
+
+			requestBudget = handler.messageBudget(message)
+		}
+
+		allowed, retryAfter := handler.Limiter.Allow(requestBudget, key)
+		if !allowed {
+			writeRateLimitError(w, retryAfter)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// messageBudget returns the budget a message is counted against
+func (handler *ServerHandler) messageBudget(message *ty.Message) Budget {
+	switch {
+	case handler.WebRTC.Contains(message.Plugin):
+		return SignalBudget
+	case message.Plugin == "/broadcast" || message.Plugin == "/private":
+		return ChatBudget
+	default:
+		return CommandBudget
+	}
+}
+
+// peekMessage decodes the message of the request body and replaces the body, so
+// it can be read again by the handler
+func peekMessage(w http.ResponseWriter, r *http.Request, maxBytes int64) (*ty.Message, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	message, err := ty.DecodeToMessage(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// rateLimitResponse returns the error response for a request exceeding its budget,
+// the retryAfter detail contains the whole seconds until the next request is allowed
+func rateLimitResponse(retryAfter time.Duration) *ty.Response {
+	seconds := retrySeconds(retryAfter)
+	rspErr := ty.NewError(fmt.Errorf("%w: slow down and retry after %ds", ty.ErrRateLimited, seconds))
+
+	return ty.ErrorEvent(rspErr.WithDetail("retryAfter", strconv.Itoa(seconds)))
+}
+
+// retrySeconds rounds the retry time up to whole seconds, at least one
+func retrySeconds(retryAfter time.Duration) int {
+	return max(1, int(math.Ceil(retryAfter.Seconds())))
+}
+
+// writeRateLimitError answers with http.StatusTooManyRequests, the Retry-After header
+// in whole seconds and the error as json response
+func writeRateLimitError(w http.ResponseWriter, retryAfter time.Duration) {
+	writeResponse(w, rateLimitResponse(retryAfter))
+}
+
+// remoteIp returns the ip of the direct peer, forwarding headers are ignored since they can be forged
+func remoteIp(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
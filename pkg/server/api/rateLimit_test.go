@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+)
+
+// newTestLimiter creates a rate limiter whose command budget has the rate and burst
+func newTestLimiter(rate float64, burst int) *RateLimiter {
+	cfg := config.Default()
+	cfg.CommandRate = rate
+	cfg.CommandBurst = burst
+
+	return NewRateLimiter(config.NewHolder(cfg))
+}
+
+func TestRateLimiterBurst(t *testing.T) {
+	rl := newTestLimiter(1, 3)
+
+	for range 3 {
+		allowed, _ := rl.Allow(CommandBudget, ClientId)
+		assert.True(t, allowed)
+	}
+
+	allowed, retryAfter := rl.Allow(CommandBudget, ClientId)
+	assert.False(t, allowed)
+	assert.Greater(t, retryAfter, time.Duration(0))
+	assert.LessOrEqual(t, retryAfter, time.Second)
+
+	// every client and budget has its own bucket
+	allowed, _ = rl.Allow(CommandBudget, ClientId2)
+	assert.True(t, allowed)
+
+	allowed, _ = rl.Allow(StreamBudget, ClientId)
+	assert.True(t, allowed)
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	rl := newTestLimiter(20, 1)
+
+	allowed, _ := rl.Allow(CommandBudget, ClientId)
+	assert.True(t, allowed)
+
+	allowed, retryAfter := rl.Allow(CommandBudget, ClientId)
+	assert.False(t, allowed)
+
+	time.Sleep(retryAfter + 10*time.Millisecond)
+
+	allowed, _ = rl.Allow(CommandBudget, ClientId)
+	assert.True(t, allowed)
+
+	// the bucket never holds more than the burst
+	time.Sleep(200 * time.Millisecond)
+
+	allowed, _ = rl.Allow(CommandBudget, ClientId)
+	assert.True(t, allowed)
+
+	allowed, _ = rl.Allow(CommandBudget, ClientId)
+	assert.False(t, allowed)
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	rl := newTestLimiter(0, 1)
+
+	for range 10 {
+		allowed, _ := rl.Allow(CommandBudget, ClientId)
+		assert.True(t, allowed)
+	}
+}
+
+func TestPollingAndAdminAreRateLimited(t *testing.T) {
+	server, _ := newTestServer(t, func(cfg *config.Config) {
+		cfg.PollTimeout = config.Duration(10 * time.Millisecond)
+		cfg.PollRate, cfg.PollBurst = 0.01, 2
+		cfg.AdminRate, cfg.AdminBurst = 0.01, 2
+		cfg.AdminKey = "admin-key"
+	})
+
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	for range 2 {
+		res := request(t, http.MethodGet, server.URL+"/users/"+ClientId+"/chat", token, nil)
+		res.Body.Close()
+		assert.NotEqual(t, http.StatusTooManyRequests, res.StatusCode)
+	}
+
+	res := request(t, http.MethodGet, server.URL+"/users/"+ClientId+"/chat", token, nil)
+	res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.NotEmpty(t, res.Header.Get("Retry-After"))
+
+	// wrong admin keys use up the budget of the ip as well
+	for _, status := range []int{http.StatusForbidden, http.StatusForbidden, http.StatusTooManyRequests} {
+		res := request(t, http.MethodGet, server.URL+"/admin/stats", "wrong-key", nil)
+		res.Body.Close()
+		assert.Equal(t, status, res.StatusCode)
+	}
+
+	res = request(t, http.MethodGet, server.URL+"/admin/stats", "admin-key", nil)
+	res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+}
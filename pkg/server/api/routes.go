@@ -6,11 +6,28 @@ import "net/http"
 func (h *ServerHandler) BuildMultiplexer() http.Handler {
 	multiplexer := http.NewServeMux()
 
-	multiplexer.Handle("POST /users/{clientId}", http.HandlerFunc(h.HandleRegistry))
-	multiplexer.Handle("POST /users/{clientId}/run", h.AuthMiddleware(h.HandleMessages))
-	multiplexer.Handle("GET /users/{clientId}/chat", h.AuthMiddleware(h.HandleGetRequest))
-	multiplexer.Handle("DELETE /users/{clientId}", h.AuthMiddleware(h.HandleMessages))
-	multiplexer.Handle("POST /users/{clientId}/signal", h.AuthMiddleware(h.HandleSignals))
+	multiplexer.Handle("POST /users/{clientId}", h.RateLimitMiddleware(RegisterBudget, h.HandleRegistry))
+	multiplexer.Handle("POST /users/{clientId}/run", h.AuthMiddleware(h.RateLimitMiddleware(MessageBudget, h.HandleMessages)))
+	multiplexer.Handle("GET /users/{clientId}/chat", h.AuthMiddleware(h.RateLimitMiddleware(PollBudget, h.HandleGetRequest)))
+	multiplexer.Handle("DELETE /users/{clientId}", h.AuthMiddleware(h.RateLimitMiddleware(CommandBudget, h.HandleMessages)))
+	multiplexer.Handle("POST /users/{clientId}/signal", h.AuthMiddleware(h.RateLimitMiddleware(SignalBudget, h.HandleSignals)))
+	multiplexer.Handle("GET /users/{clientId}/ws", h.AuthMiddleware(h.RateLimitMiddleware(StreamBudget, h.HandleWebSocket)))
+	multiplexer.Handle("GET /users/{clientId}/events", h.AuthMiddleware(h.RateLimitMiddleware(StreamBudget, h.HandleEventStream)))
+	multiplexer.Handle("POST /users/{clientId}/resume", h.AuthMiddleware(h.RateLimitMiddleware(StreamBudget, h.HandleResume)))
+	multiplexer.Handle("POST /users/{clientId}/token", h.AuthMiddleware(h.RateLimitMiddleware(StreamBudget, h.HandleRefreshToken)))
+
+	multiplexer.Handle("GET /admin/clients", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleListClients)))
+	multiplexer.Handle("POST /admin/clients/{clientId}/kick", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleKick)))
+	multiplexer.Handle("POST /admin/clients/{clientId}/ban", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleBan)))
+	multiplexer.Handle("GET /admin/bans", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleListBans)))
+	multiplexer.Handle("DELETE /admin/bans/{name}", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleUnban)))
+	multiplexer.Handle("GET /admin/groups", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleListGroups)))
+	multiplexer.Handle("DELETE /admin/groups/{groupId}", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleDeleteGroup)))
+	multiplexer.Handle("PUT /admin/groups/{groupId}/persistent", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleSetPersistent)))
+	multiplexer.Handle("POST /admin/notice", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleNotice)))
+	multiplexer.Handle("GET /admin/stats", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleDeliveryStats)))
+	multiplexer.Handle("PUT /admin/maxUsers", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleSetMaxUsers)))
+	multiplexer.Handle("POST /admin/tokens/revoke", h.RateLimitMiddleware(AdminBudget, h.AdminMiddleware(h.HandleRevokeToken)))
 
 	return multiplexer
 }
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	chat "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/chat"
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+var (
+	ClientName  = "Arndt"
+	ClientName2 = "Len"
+	ClientId    = "clientId-DyGWNnLrLWnbuhf-LgBUAdAxdZf-U1pgRw"
+	ClientId2   = "clientId2-yGWNnLrLWnbuhf-LgBUAdAxdZf-U1pgRw"
+)
+
+// newTestServer serves a chat service with the default config, modify may change the config first
+func newTestServer(t *testing.T, modify func(cfg *config.Config)) (*httptest.Server, *chat.ChatService) {
+	cfg := config.Default()
+	cfg.RegisterBurst = 100
+	cfg.CommandBurst = 100
+	if modify != nil {
+		modify(cfg)
+	}
+
+	holder := config.NewHolder(cfg)
+	service := chat.NewChatService(holder, chat.NewMemoryStore(100), chat.NewTokenManager("", time.Minute))
+
+	accounts, err := chat.NewAccountStore("")
+	assert.Nil(t, err)
+
+	handler := NewServerHandler(holder, service, chat.RegisterPlugins(service, accounts, holder), chat.RegisterCallPlugins(service))
+
+	server := httptest.NewServer(handler.BuildMultiplexer())
+	t.Cleanup(server.Close)
+
+	return server, service
+}
+
+// registerClient registers a guest and returns its token
+func registerClient(t *testing.T, url string, clientId string, name string) string {
+	body, err := json.Marshal(ty.Message{Name: name, Plugin: "/register", Content: name, ClientId: clientId})
+	assert.Nil(t, err)
+
+	res, err := http.Post(url+"/users/"+clientId, "application/json", bytes.NewReader(body))
+	assert.Nil(t, err)
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	assert.Nil(t, err)
+
+	rsp, err := ty.DecodeToResponse(data)
+	assert.Nil(t, err)
+	assert.NotEmpty(t, rsp.Content)
+
+	return rsp.Content
+}
+
+// request sends a request authorized with the token
+func request(t *testing.T, method string, url string, token string, header http.Header) *http.Response {
+	req, err := http.NewRequest(method, url, nil)
+	assert.Nil(t, err)
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Authorization", token)
+
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+
+	return res
+}
+
+// run posts a message of the client to the run endpoint
+func run(t *testing.T, url string, clientId string, token string, plugin string, content string) *http.Response {
+	body, err := json.Marshal(ty.Message{Plugin: plugin, Content: content, ClientId: clientId})
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, url+"/users/"+clientId+"/run", bytes.NewReader(body))
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+
+	return res
+}
+
+// signal posts the signal of the client to the signal endpoint
+func signal(t *testing.T, url string, clientId string, token string, message ty.Message) *http.Response {
+	body, err := json.Marshal(message)
+	assert.Nil(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, url+"/users/"+clientId+"/signal", bytes.NewReader(body))
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+
+	return res
+}
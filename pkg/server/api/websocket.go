@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	chat "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/chat"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// HandleWebSocket upgrades the request to a websocket connection, which streams every response
+// of the client as a json frame and accepts messages as json frames in return. Responses up to the
+// since query parameter are acknowledged and unacknowledged ones after it are redelivered first
+// should receive a Path Parameter with clientId in it
+func (handler *ServerHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	clientId := r.PathValue("clientId")
+	if clientId == "" {
+		writeError(w, fmt.Errorf("%w: missing path parameter clientId", ty.ErrEmptyString))
+		return
+	}
+
+	since, resumed, err := parseCursor(r.URL.Query().Get("since"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	client, err := handler.Service.GetClient(clientId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var pending []*ty.Response
+	if resumed {
+		pending = redeliver(client, since)
+	}
+
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			handler.serveWebSocket(ws, clientId, client, pending)
+		},
+	}
+
+	server.ServeHTTP(w, r)
+}
+
+// serveWebSocket writes the pending and then every received response into the websocket until
+// the connection or the clients channel is closed or the token of the connection is no longer valid
+func (handler *ServerHandler) serveWebSocket(ws *websocket.Conn, clientId string, client *chat.Client, pending []*ty.Response) {
+	defer ws.Close()
+
+	// the read and write timeouts of the http server would otherwise end the stream
+	ws.SetDeadline(time.Time{})
+	ws.MaxPayloadBytes = int(handler.Config.Get().MaxBodyBytes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	token := ws.Request().Header.Get("Authorization")
+
+	go handler.webSocketReader(ws, clientId, client, cancel)
+
+	for _, rsp := range pending {
+		err := websocket.JSON.Send(ws, rsp)
+		if err != nil {
+			fmt.Printf("\n%v: websocket of %s closed", err, clientId)
+			return
+		}
+	}
+
+	for {
+		receiveCtx, receiveCancel := context.WithTimeout(ctx, streamTimeout)
+		rsp, err := client.Receive(receiveCtx)
+		receiveCancel()
+
+		if errors.Is(err, ty.ErrChannelClosed) || ctx.Err() != nil {
+			return
+		}
+
+		if !handler.tokenValid(clientId, token) {
+			// the unsent response stays unacknowledged and is redelivered to the next connection
+			return
+		}
+
+		if err != nil {
+			continue
+		}
+
+		err = websocket.JSON.Send(ws, rsp)
+		if err != nil {
+			fmt.Printf("\n%v: websocket of %s closed", err, clientId)
+			return
+		}
+	}
+}
+
+// webSocketReader receives messages from the websocket and executes them until the connection
+// is closed, then cancels the writing side
+func (handler *ServerHandler) webSocketReader(ws *websocket.Conn, clientId string, client *chat.Client, cancel context.CancelFunc) {
+	defer cancel()
+
+	for {
+		message := ty.Message{}
+
+		err := websocket.JSON.Receive(ws, &message)
+		if err != nil {
+			return
+		}
+
+		handler.dispatchWebSocketMessage(ws.Request(), clientId, client, &message)
+	}
+}
+
+// dispatchWebSocketMessage executes a message with the call plugins if it is a webRTC signal
+// or with the chat plugins otherwise and echoes the result to the client, r is the upgraded request
+func (handler *ServerHandler) dispatchWebSocketMessage(r *http.Request, clientId string, client *chat.Client, message *ty.Message) {
+	allowed, retryAfter := handler.Limiter.Allow(handler.messageBudget(message), clientId)
+	if !allowed {
+		handler.Service.Echo(clientId, rateLimitResponse(retryAfter))
+		return
+	}
+
+	ctx := handler.callerContext(r, client)
+
+	if handler.WebRTC.Contains(message.Plugin) {
+		_, err := client.Execute(ctx, handler.WebRTC, message)
+
+		switch {
+		case err == nil:
+		case isPermissionError(err):
+			// a spoofed signal mustn't reach the claimed participants
+			handler.Service.Echo(clientId, ty.ErrorEvent(err))
+		default:
+			handler.echoSignalError(clientId, message, err)
+		}
+
+		return
+	}
+
+	rsp, err := client.Execute(ctx, handler.Plugins, message)
+	if err != nil {
+		rsp = ty.ErrorEvent(err)
+	}
+
+	if rsp == nil {
+		return
+	}
+
+	err = handler.Service.Echo(clientId, rsp)
+	if err != nil {
+		fmt.Printf("\n%v: websocket response couldn't be echoed to %s", err, clientId)
+	}
+}
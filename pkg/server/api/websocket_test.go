@@ -0,0 +1,82 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// dialWebSocket connects the client to its websocket, query is appended to the url
+func dialWebSocket(t *testing.T, url string, clientId string, token string, query string) *websocket.Conn {
+	config, err := websocket.NewConfig(strings.Replace(url, "http", "ws", 1)+"/users/"+clientId+"/ws"+query, url)
+	assert.Nil(t, err)
+	config.Header.Set("Authorization", token)
+
+	ws, err := websocket.DialConfig(config)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { ws.Close() })
+
+	return ws
+}
+
+// receiveFrame receives the next response of the websocket and fails the test if it doesn't arrive in time
+func receiveFrame(t *testing.T, ws *websocket.Conn) *ty.Response {
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+
+	rsp := &ty.Response{}
+	if !assert.Nil(t, websocket.JSON.Receive(ws, rsp)) {
+		t.FailNow()
+	}
+
+	return rsp
+}
+
+func TestWebSocketDispatch(t *testing.T) {
+	server, _ := newTestServer(t, nil)
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	ws := dialWebSocket(t, server.URL, ClientId, token, "")
+
+	// messages are executed with the chat plugins and answered through the websocket
+	assert.Nil(t, websocket.JSON.Send(ws, ty.Message{Plugin: "/users", ClientId: ClientId}))
+	assert.Equal(t, ty.KindUsers, receiveFrame(t, ws).Kind)
+
+	assert.Nil(t, websocket.JSON.Send(ws, ty.Message{Plugin: "/unknown", ClientId: ClientId}))
+	assert.Equal(t, ty.KindError, receiveFrame(t, ws).Kind)
+
+	// signals are executed with the call plugins, which need a group
+	assert.Nil(t, websocket.JSON.Send(ws, ty.Message{Name: ClientId, Plugin: "/" + ty.FailedConnectionFlag, ClientId: ClientId}))
+	assert.Equal(t, ty.KindError, receiveFrame(t, ws).Kind)
+}
+
+func TestWebSocketRedeliversSince(t *testing.T) {
+	server, service := newTestServer(t, nil)
+	token := registerClient(t, server.URL, ClientId, ClientName)
+
+	assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent("first")))
+	assert.Nil(t, service.Echo(ClientId, ty.NoticeEvent("second")))
+
+	// the responses are received, but the connection breaks before they are acknowledged
+	ws := dialWebSocket(t, server.URL, ClientId, token, "")
+	first := receiveFrame(t, ws)
+	assert.Equal(t, "first", first.Content)
+	assert.Equal(t, "second", receiveFrame(t, ws).Content)
+	ws.Close()
+
+	// everything after the cursor of the next connection is redelivered
+	ws = dialWebSocket(t, server.URL, ClientId, token, "?since=0")
+	assert.Equal(t, "first", receiveFrame(t, ws).Content)
+	assert.Equal(t, "second", receiveFrame(t, ws).Content)
+	ws.Close()
+
+	ws = dialWebSocket(t, server.URL, ClientId, token, "?since="+strconv.FormatUint(first.Seq, 10))
+	assert.Equal(t, "second", receiveFrame(t, ws).Content)
+}
@@ -0,0 +1,156 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// Account is a durable user identity protected by a hashed password
+type Account struct {
+	Name         string    `json:"name"`
+	PasswordHash []byte    `json:"passwordHash"`
+	Created      time.Time `json:"created"`
+}
+
+// AccountStore holds every account and persists them as json file if a path is set
+type AccountStore struct {
+	// key is the lowercased account name, so names are unique regardless of case
+	accounts map[string]*Account
+	path     string
+	mu       sync.RWMutex
+}
+
+// NewAccountStore loads the accounts from the file at path, if path is empty
+// the accounts are only kept in memory
+func NewAccountStore(path string) (*AccountStore, error) {
+	as := &AccountStore{accounts: make(map[string]*Account), path: path}
+	if path == "" {
+		return as, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return as, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: account file couldn't be read", err)
+	}
+
+	var accounts []*Account
+
+	err = json.Unmarshal(data, &accounts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: account file couldn't be parsed", err)
+	}
+
+	for _, account := range accounts {
+		as.accounts[accountKey(account.Name)] = account
+	}
+
+	return as, nil
+}
+
+// NewAccount hashes the password of a new account, which isn't stored until it is added
+func NewAccount(name string, password string) (*Account, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("%w: password couldn't be hashed", err)
+	}
+
+	return &Account{Name: name, PasswordHash: hash, Created: time.Now().UTC()}, nil
+}
+
+// Add stores the account if its name is still free
+func (as *AccountStore) Add(account *Account) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	key := accountKey(account.Name)
+	if _, exists := as.accounts[key]; exists {
+		return fmt.Errorf("%w: the name %s is already taken", ty.ErrNoPermission, account.Name)
+	}
+
+	as.accounts[key] = account
+
+	err := as.saveRequireLock()
+	if err != nil {
+		delete(as.accounts, key)
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate returns the account if the password matches its hash
+func (as *AccountStore) Authenticate(name string, password string) (*Account, error) {
+	as.mu.RLock()
+	account, exists := as.accounts[accountKey(name)]
+	as.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: wrong name or password", ty.ErrNoPermission)
+	}
+
+	err := bcrypt.CompareHashAndPassword(account.PasswordHash, []byte(password))
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong name or password", ty.ErrNoPermission)
+	}
+
+	return account, nil
+}
+
+// Exists tells if there is an account with the given name
+func (as *AccountStore) Exists(name string) bool {
+	as.mu.RLock()
+	defer as.mu.RUnlock()
+
+	_, exists := as.accounts[accountKey(name)]
+	return exists
+}
+
+// saveRequireLock writes all accounts into a temporary file and replaces the account file with it
+func (as *AccountStore) saveRequireLock() error {
+	if as.path == "" {
+		return nil
+	}
+
+	accounts := make([]*Account, 0, len(as.accounts))
+	for _, account := range as.accounts {
+		accounts = append(accounts, account)
+	}
+
+	data, err := json.MarshalIndent(accounts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: error parsing accounts to json", err)
+	}
+
+	tmpPath := as.path + ".tmp"
+
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("%w: account file couldn't be written", err)
+	}
+
+	err = os.Rename(tmpPath, as.path)
+	if err != nil {
+		return fmt.Errorf("%w: account file couldn't be replaced", err)
+	}
+
+	return nil
+}
+
+// accountKey normalizes the name like sameName does, so names differing in case or
+// whitespace belong to the same account
+func accountKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
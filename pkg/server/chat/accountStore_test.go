@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestAccountStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "accounts.json")
+
+	accounts, err := NewAccountStore(path)
+	assert.Nil(t, err)
+
+	account, err := NewAccount(ClientName, Password)
+	assert.Nil(t, err)
+	assert.NotEqual(t, []byte(Password), account.PasswordHash)
+	assert.False(t, accounts.Exists(ClientName))
+
+	assert.Nil(t, accounts.Add(account))
+	assert.True(t, accounts.Exists("arndt"))
+
+	duplicate, err := NewAccount("ARNDT", Password)
+	assert.Nil(t, err)
+	assert.ErrorIs(t, accounts.Add(duplicate), ty.ErrNoPermission)
+
+	// the accounts are loaded from the file again
+	accounts, err = NewAccountStore(path)
+	assert.Nil(t, err)
+
+	_, err = accounts.Authenticate(ClientName, "wrong password")
+	assert.ErrorIs(t, err, ty.ErrNoPermission)
+
+	account, err = accounts.Authenticate("arndt", Password)
+	assert.Nil(t, err)
+	assert.Equal(t, ClientName, account.Name)
+}
@@ -0,0 +1,229 @@
+package chat
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// ClientInfo describes a client for server operators
+type ClientInfo struct {
+	ClientId string `json:"clientId"`
+	Name     string `json:"name"`
+	Account  string `json:"account,omitempty"`
+	GroupId  string `json:"groupId,omitempty"`
+	// ids of every group the client is a member of, GroupId is the active one
+	Groups       []string  `json:"groups"`
+	LastActivity time.Time `json:"lastActivity"`
+	Disconnected bool      `json:"disconnected"`
+	// chat and priority responses waiting to be received
+	Queued   int `json:"queued"`
+	Priority int `json:"priority"`
+	// chat responses dropped since the last receive
+	Dropped int  `json:"dropped"`
+	Evicted bool `json:"evicted"`
+}
+
+// GroupInfo describes a group with its members and rtc pairs for server operators
+type GroupInfo struct {
+	GroupId    string       `json:"groupId"`
+	Name       string       `json:"name"`
+	Persistent bool         `json:"persistent"`
+	Topic      string       `json:"topic,omitempty"`
+	Members    []ClientInfo `json:"members"`
+	// both clientIds of every rtc and if it is ICE connected
+	Calls []CallInfo `json:"calls"`
+}
+
+type CallInfo struct {
+	ClientIds []string `json:"clientIds"`
+	Connected bool     `json:"connected"`
+}
+
+// Clients returns every client sorted by name
+func (s *ChatService) Clients() []ClientInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	clients := make([]ClientInfo, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, client.info())
+	}
+
+	slices.SortFunc(clients, func(a, b ClientInfo) int { return strings.Compare(a.Name, b.Name) })
+
+	return clients
+}
+
+// Groups returns every group with its members and rtcs sorted by name
+func (s *ChatService) Groups() []GroupInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make([]GroupInfo, 0, len(s.groups))
+	for _, group := range s.groups {
+		groups = append(groups, group.info())
+	}
+
+	slices.SortFunc(groups, func(a, b GroupInfo) int { return strings.Compare(a.Name, b.Name) })
+
+	return groups
+}
+
+// Kick informs the client that it was removed by the server and logs it out
+func (s *ChatService) Kick(clientId string) (*Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.kickRequireLock(clientId)
+}
+
+func (s *ChatService) kickRequireLock(clientId string) (*Client, error) {
+	client, exists := s.clients[clientId]
+	if !exists {
+		return nil, fmt.Errorf("%w: there is no client with id: %s registered", ty.ErrNotAvailable, clientId)
+	}
+
+	client.Send(ty.ErrorEvent(fmt.Errorf("%w: Du wurdest vom Server entfernt", ty.ErrNoPermission)))
+	s.removeClientRequireLock(client)
+
+	fmt.Printf("\nkicked client %s", client.GetName())
+
+	go s.Broadcast(nil, ty.UserLeftEvent(clientId, client.GetName()))
+
+	return client, nil
+}
+
+// Ban prevents the account of the client, or its name if it isn't logged in, from registering
+// again and kicks it. The ban is recorded first, so the client can't register again in between
+func (s *ChatService) Ban(clientId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	client, exists := s.clients[clientId]
+	if !exists {
+		return fmt.Errorf("%w: there is no client with id: %s registered", ty.ErrNotAvailable, clientId)
+	}
+
+	// logged in clients can change their name, but not their account
+	name := client.GetAccount()
+	if name == "" {
+		name = client.GetName()
+	}
+
+	s.banned[strings.ToLower(name)] = true
+	fmt.Printf("\nbanned name %s", name)
+
+	_, err := s.kickRequireLock(clientId)
+
+	return err
+}
+
+// Unban allows a banned name to register again
+func (s *ChatService) Unban(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strings.ToLower(name)
+	if !s.banned[key] {
+		return fmt.Errorf("%w: the name %s isn't banned", ty.ErrNotAvailable, name)
+	}
+
+	delete(s.banned, key)
+
+	return nil
+}
+
+// Bans returns every banned name sorted
+func (s *ChatService) Bans() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	bans := make([]string, 0, len(s.banned))
+	for name := range s.banned {
+		bans = append(bans, name)
+	}
+
+	slices.Sort(bans)
+
+	return bans
+}
+
+// DeleteGroup removes every member from the group, informs them and deletes the group
+func (s *ChatService) DeleteGroup(groupId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, exists := s.groups[groupId]
+	if !exists {
+		return fmt.Errorf("%w: there is no group with id: %s registered", ty.ErrNotAvailable, groupId)
+	}
+
+	for _, client := range group.GetClients() {
+		group.RemoveClient(client)
+		group.RemoveConnection(client.ClientId, "", true)
+		client.RemoveGroup(group)
+		client.Send(ty.GroupLeftEvent(group.toJson(), "Die Gruppe wurde vom Server gelöscht"))
+	}
+
+	delete(s.groups, groupId)
+	fmt.Printf("\ndeleted group %s", groupId)
+
+	return s.saveGroupsRequireLock()
+}
+
+// Notice sends a server notice to every client, regardless if it is in a group
+func (s *ChatService) Notice(content string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, client := range s.clients {
+		err := client.Send(ty.NoticeEvent(fmt.Sprintf("[Server] %s", content)))
+		if err != nil {
+			fmt.Printf("\n%v: notice -> %s", err, client.GetName())
+		}
+	}
+}
+
+func (c *Client) info() ClientInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return ClientInfo{
+		ClientId:     c.ClientId,
+		Name:         c.Name,
+		Account:      c.account,
+		GroupId:      c.groupId,
+		Groups:       slices.Sorted(maps.Keys(c.groups)),
+		LastActivity: c.lastSign,
+		Disconnected: c.disconnected,
+		Queued:       len(c.clientCh),
+		Priority:     len(c.priority),
+		Dropped:      c.dropped,
+		Evicted:      c.evicted,
+	}
+}
+
+func (g *Group) info() GroupInfo {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	info := GroupInfo{GroupId: g.GroupId, Name: g.Name, Persistent: g.Persistent, Topic: g.Topic,
+		Members: []ClientInfo{}, Calls: []CallInfo{}}
+
+	for _, client := range g.clients {
+		info.Members = append(info.Members, client.info())
+	}
+
+	for compKey, connected := range g.rtcs {
+		info.Calls = append(info.Calls, CallInfo{ClientIds: strings.Split(compKey, ":"), Connected: connected})
+	}
+
+	slices.SortFunc(info.Members, func(a, b ClientInfo) int { return strings.Compare(a.Name, b.Name) })
+
+	return info
+}
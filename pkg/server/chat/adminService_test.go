@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestBanGuest(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	register(t, pr, ClientId, ClientName)
+
+	assert.Nil(t, service.Ban(ClientId))
+	assert.Equal(t, []string{"arndt"}, service.Bans())
+
+	_, err := service.GetClient(ClientId)
+	assert.ErrorIs(t, err, ty.ErrNotAvailable)
+
+	rsp := tryRegister(t, pr, ClientId2, ClientName)
+	assert.ErrorIs(t, rsp.Error, ty.ErrNoPermission)
+
+	assert.Nil(t, service.Unban(ClientName))
+	register(t, pr, ClientId2, ClientName)
+}
+
+func TestBanAccount(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	rsp := execute(t, pr, ClientId, "/signup", ClientName+" "+Password)
+	assert.Nil(t, rsp.Error)
+
+	rsp = execute(t, pr, ClientId, "/nick", ClientName2)
+	assert.Nil(t, rsp.Error)
+
+	// the ban applies to the account instead of the current name
+	assert.Nil(t, service.Ban(ClientId))
+	assert.Equal(t, []string{"arndt"}, service.Bans())
+
+	rsp = execute(t, pr, ClientId2, "/login", ClientName+" "+Password)
+	assert.ErrorIs(t, rsp.Error, ty.ErrNoPermission)
+
+	register(t, pr, ClientId3, ClientName2)
+}
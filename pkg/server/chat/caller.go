@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// Caller is the identity of the client executing a plugin, it is verified by the api layer
+// from the path and the auth token and is the only trusted source of the sender
+type Caller struct {
+	ClientId string
+	// empty if the caller isn't registered yet
+	Name string
+	// set if the caller authenticated as registered client
+	Registered bool
+}
+
+type callerKey struct{}
+
+// WithCaller returns a copy of the context carrying the verified caller
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the verified caller of the context or an empty Caller if there is none
+func CallerFrom(ctx context.Context) Caller {
+	caller, _ := ctx.Value(callerKey{}).(Caller)
+	return caller
+}
+
+// Caller returns the identity of the client
+func (c *Client) Caller() Caller {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return Caller{ClientId: c.ClientId, Name: c.Name, Registered: true}
+}
+
+// IdentityError is returned if a message body claims another sender than the verified caller
+type IdentityError struct {
+	// json name of the message field
+	Field    string
+	Claimed  string
+	Verified string
+}
+
+func (e *IdentityError) Error() string {
+	return fmt.Sprintf("%v: message %s '%s' doesn't match the verified caller '%s'", ty.ErrNoPermission, e.Field, e.Claimed, e.Verified)
+}
+
+func (e *IdentityError) Unwrap() error {
+	return ty.ErrNoPermission
+}
+
+func (e *IdentityError) ErrorDetails() map[string]string {
+	return map[string]string{"field": e.Field, "claimed": e.Claimed, "verified": e.Verified}
+}
+
+// checkClaim returns an IdentityError if the claimed value is set and differs from the verified one
+func checkClaim(field string, claimed string, verified string) error {
+	if claimed == "" || verified == "" || claimed == verified {
+		return nil
+	}
+
+	return &IdentityError{Field: field, Claimed: claimed, Verified: verified}
+}
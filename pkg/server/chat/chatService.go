@@ -1,98 +1,458 @@
 package chat
 
 import (
+	"crypto/subtle"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
 
 // clients who communicate with the sever
 type ChatService struct {
-	clients  map[string]*Client
-	groups   map[string]*Group
-	maxUsers int
-	mu       sync.RWMutex
+	clients map[string]*Client
+	groups  map[string]*Group
+	// pending group invitations, key: clientId of the invitee, then groupId
+	invites map[string]map[string]*Invite
+	config  *config.Holder
+	store   MessageStore
+	tokens  *TokenManager
+	stats   *DeliveryStats
+	// lowercased names which aren't allowed to register
+	banned map[string]bool
+	// the persistent groups are saved into it, nil if they aren't stored
+	groupStore *GroupStore
+	mu         sync.RWMutex
 }
 
-func NewChatService(maxUsers int) *ChatService {
+func NewChatService(cfg *config.Holder, store MessageStore, tokens *TokenManager) *ChatService {
 	return &ChatService{
-		clients:  make(map[string]*Client),
-		groups:   make(map[string]*Group),
-		maxUsers: maxUsers,
+		clients: make(map[string]*Client),
+		groups:  make(map[string]*Group),
+		invites: make(map[string]map[string]*Invite),
+		config:  cfg,
+		store:   store,
+		tokens:  tokens,
+		stats:   &DeliveryStats{},
+		banned:  make(map[string]bool),
 	}
 }
 
+// Broadcast sends the response to the given clients except its sender. A nil map broadcasts
+// into the lobby, which reaches every client, clients in a group route it by themselves
 func (s *ChatService) Broadcast(clientsToIterate map[string]*Client, rsp *ty.Response) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	switch clientsToIterate {
-	case nil:
+	if clientsToIterate == nil {
 		clientsToIterate = s.clients
-		for _, client := range clientsToIterate {
-			if client.ClientId != rsp.ClientId && client.GetGroupId() == "" {
-				err := client.Send(rsp)
-				if err != nil {
-					fmt.Printf("\n%v: %s -> %s", err, rsp.RspName, client.Name)
-				}
+	}
+
+	for _, client := range clientsToIterate {
+		if client.ClientId != rsp.ClientId {
+			err := client.Send(rsp)
+			if err != nil {
+				fmt.Printf("\n%v: %s -> %s", err, rsp.RspName, client.GetName())
 			}
 		}
-	default:
-		for _, client := range clientsToIterate {
-			if client.ClientId != rsp.ClientId {
-				err := client.Send(rsp)
-				if err != nil {
-					fmt.Printf("\n%v: %s -> %s", err, rsp.RspName, client.Name)
-				}
-			}
+	}
+}
+
+// addClientRequireLock creates a client with a fresh authToken, puts it into the clients map
+// and announces it in the lobby. The response contains the name and token or an error
+func (s *ChatService) addClientRequireLock(name string, clientId string, account string) *ty.Response {
+	maxUsers := s.config.Get().MaxUsers
+	if len(s.clients) >= maxUsers {
+		return ty.ErrorEvent(fmt.Errorf("%w: usercap %d reached, try again later. users:%d", ty.ErrNoPermission, maxUsers, len(s.clients)))
+	}
+
+	if _, exists := s.clients[clientId]; exists {
+		return ty.ErrorEvent(fmt.Errorf("%w: client already registered", ty.ErrNoPermission))
+	}
+
+	err := s.checkNameRequireLock(name, clientId)
+	if err != nil {
+		return ty.ErrorEvent(err)
+	}
+
+	token, err := s.tokens.Issue(clientId)
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: token couldn't be issued", err))
+	}
+
+	cfg := s.config.Get()
+	clientCh := make(chan *ty.Response, cfg.ChannelBuffer)
+	client := &Client{
+		Name:      name,
+		ClientId:  clientId,
+		GroupName: "",
+		groupId:   "",
+		groups:    make(map[string]*Group),
+		account:   account,
+		clientCh:  clientCh,
+		notify:    make(chan struct{}, 1),
+		policy:    newDeliveryPolicy(cfg),
+		stats:     s.stats,
+		active:    true,
+		authToken: token,
+		lastSign:  time.Now().UTC(),
+		chClosed:  false,
+		rtcs:      make(map[string]string),
+		mu:        sync.RWMutex{},
+	}
+	s.clients[clientId] = client
+
+	fmt.Printf("\nnew client '%s' registered.", name)
+
+	go s.Broadcast(nil, ty.UserJoinedEvent(clientId, client.Name))
+
+	return &ty.Response{RspName: name, Content: token}
+}
+
+// loggedInRequireLock reports whether a client is logged in with the account, disconnected clients
+// count as well, since they can still resume their session
+func (s *ChatService) loggedInRequireLock(account string) bool {
+	for _, client := range s.clients {
+		if strings.EqualFold(client.GetAccount(), account) {
+			return true
 		}
 	}
+
+	return false
 }
 
-// InactiveObjectDeleter searches for idle clients or groups and deletes them as well as closes their message-channel
-func (s *ChatService) InactiveObjectDeleter(timeLimit time.Duration) {
+// checkNameRequireLock returns an error if the name is reserved, banned or used by another client
+// than clientId. Names are compared regardless of case, disconnected clients keep their name
+// until they are deleted, so they can resume their session
+func (s *ChatService) checkNameRequireLock(name string, clientId string) error {
+	for _, reserved := range s.config.Get().ReservedNames {
+		if sameName(reserved, name) {
+			return fmt.Errorf("%w: the name %s is reserved", ty.ErrNoPermission, name)
+		}
+	}
+
+	if s.banned[strings.ToLower(name)] {
+		return fmt.Errorf("%w: the name %s is banned from this server", ty.ErrNoPermission, name)
+	}
+
+	for _, client := range s.clients {
+		if client.ClientId != clientId && sameName(client.GetName(), name) {
+			return fmt.Errorf("%w: the name %s is already taken", ty.ErrNoPermission, name)
+		}
+	}
+
+	return nil
+}
+
+// RenameClient changes the name of the client and announces it in the lobby and in the groups of the
+// client. The rename event is returned as response for the client, who is skipped by the broadcast
+func (s *ChatService) RenameClient(client *Client, name string) (*ty.Response, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	err := s.checkNameRequireLock(name, client.ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	oldName := client.SetName(name)
+
+	fmt.Printf("\nclient %s renamed from %s to %s", client.ClientId, oldName, name)
+
+	// the lobby reaches every client, so every client is only notified once
+	rsp := ty.UserRenamedEvent(client.ClientId, oldName, name)
+	go s.Broadcast(nil, rsp)
+
+	return rsp, nil
+}
+
+// Record timestamps a chat message and puts it into the message store
+func (s *ChatService) Record(msg ty.JsonMessage) {
+	msg.Time = time.Now().UTC()
+
+	err := s.store.Append(msg)
+	if err != nil {
+		fmt.Printf("\n%v: message couldn't be recorded", err)
+	}
+}
+
+// History returns up to n of the newest recorded messages matching the filter
+func (s *ChatService) History(n int, filter func(ty.JsonMessage) bool) ([]ty.JsonMessage, error) {
+	return s.store.Last(n, filter)
+}
+
+// InactiveObjectDeleter searches for idle clients or groups. Clients idle for the configured timeLimit
+// are marked as disconnected but keep their state, so they can resume their session. After the
+// gracePeriod they are deleted as well as their message-channel closed. Expired invitations are deleted too
+func (s *ChatService) InactiveObjectDeleter() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := s.config.Get()
+	timeLimit := time.Duration(cfg.TimeLimit)
+	gracePeriod := time.Duration(cfg.GracePeriod)
+
 	for clientId, client := range s.clients {
-		if client.Idle(timeLimit) {
-			if client.groupId != "" {
-				s.groups[client.groupId].RemoveClient(client)
-			}
+		switch {
+		case client.IsEvicted():
+			fmt.Printf("\nlogging out evicted client %s", clientId)
+			s.removeClientRequireLock(client)
 
+			go s.Broadcast(nil, ty.UserLeftEvent(clientId, client.GetName()))
+
+		case client.Idle(timeLimit + gracePeriod):
 			fmt.Printf("\nlogging out inactive client %s", clientId)
-			client.Close()
-			delete(s.clients, clientId)
+			s.removeClientRequireLock(client)
+
+		case client.Idle(timeLimit):
+			if client.SetDisconnected(true) {
+				fmt.Printf("\nclient %s disconnected, keeping session for %v", clientId, gracePeriod)
+			}
 		}
 	}
 
 	for groupId, group := range s.groups {
-		if group.SetSize() < 1 {
+		if group.SetSize() < 1 && !group.IsPersistent() {
 			fmt.Printf("\ndeleting empty group %s", groupId)
 			delete(s.groups, groupId)
 		}
 	}
+
+	err := s.saveGroupsRequireLock()
+	if err != nil {
+		fmt.Printf("\n%v", err)
+	}
+
+	s.pruneInvitesRequireLock(time.Now().UTC())
+	s.tokens.PruneRevoked()
+}
+
+// RestoreGroups creates the groups of the store, the persistent groups are saved into it from now on
+func (s *ChatService) RestoreGroups(store *GroupStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.groupStore = store
+
+	records := store.Records()
+	for _, record := range records {
+		s.groups[record.GroupId] = groupFromRecord(record)
+	}
+
+	fmt.Printf("\nrestored %d persistent groups", len(records))
+}
+
+// SetGroupPersistent flags the group as persistent, so it is kept while empty and saved, or
+// as ad-hoc group, which is deleted once it is empty
+func (s *ChatService) SetGroupPersistent(groupId string, persistent bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, exists := s.groups[groupId]
+	if !exists {
+		return fmt.Errorf("%w: there is no group with id: %s registered", ty.ErrNotAvailable, groupId)
+	}
+
+	group.SetPersistent(persistent)
+
+	fmt.Printf("\ngroup %s persistent: %t", groupId, persistent)
+
+	return s.saveGroupsRequireLock()
+}
+
+// SetGroupTopic sets the topic of the group, which is saved if the group is persistent
+func (s *ChatService) SetGroupTopic(group *Group, topic string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group.SetTopic(topic)
+
+	return s.saveGroupsRequireLock()
+}
+
+// SetGroupBanned bans or unbans the client and its account from the group, account bans are saved if the group is persistent
+func (s *ChatService) SetGroupBanned(group *Group, clientId string, account string, banned bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := group.SetBanned(clientId, account, banned)
+	if err != nil {
+		return err
+	}
+
+	return s.saveGroupsRequireLock()
+}
+
+// RenameGroup renames the group for its members, it is saved if the group is persistent
+func (s *ChatService) RenameGroup(group *Group, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group.Rename(name)
+
+	for _, client := range group.GetClients() {
+		client.RefreshGroupName(group)
+	}
+
+	fmt.Printf("\ngroup %s renamed to %s", group.GroupId, name)
+
+	return s.saveGroupsRequireLock()
+}
+
+// saveGroupsRequireLock saves every persistent group into the group store
+func (s *ChatService) saveGroupsRequireLock() error {
+	if s.groupStore == nil {
+		return nil
+	}
+
+	records := []*GroupRecord{}
+	for _, group := range s.groups {
+		if group.IsPersistent() {
+			records = append(records, group.toRecord())
+		}
+	}
+
+	return s.groupStore.Save(records)
+}
+
+// removeClientRequireLock removes the client from its groups and the clients map,
+// closes its channel and revokes its token
+func (s *ChatService) removeClientRequireLock(client *Client) {
+	for _, group := range client.GetGroups() {
+		newOwnerId, _ := group.RemoveClient(client)
+		group.RemoveConnection(client.ClientId, "", true)
+		client.RemoveGroup(group)
+
+		go announceOwner(s, group, newOwnerId)
+	}
+
+	client.Close()
+	s.tokens.Revoke(client.GetAuthToken())
+	delete(s.clients, client.ClientId)
+	delete(s.invites, client.ClientId)
+}
+
+// Authenticate returns the client if the token is valid and the current token of the client
+func (s *ChatService) Authenticate(clientId string, token string) (*Client, error) {
+	client, err := s.GetClient(clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.tokens.Verify(token, clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(token), []byte(client.GetAuthToken())) != 1 {
+		return nil, fmt.Errorf("%w: token doesn't match", ty.ErrNoPermission)
+	}
+
+	return client, nil
+}
+
+// RefreshToken issues a new token for the client and revokes the old one
+func (s *ChatService) RefreshToken(clientId string) (*ty.Response, error) {
+	client, err := s.GetClient(clientId)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.tokens.Issue(clientId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token couldn't be issued", err)
+	}
+
+	oldToken := client.SetAuthToken(token)
+	s.tokens.Revoke(oldToken)
+
+	return &ty.Response{RspName: client.GetName(), Content: token}, nil
+}
+
+// RevokeToken puts a token onto the revocation list, if a clientId is given instead
+// the current token of that client is revoked
+func (s *ChatService) RevokeToken(token string, clientId string) error {
+	if token == "" {
+		client, err := s.GetClient(clientId)
+		if err != nil {
+			return err
+		}
+
+		token = client.GetAuthToken()
+	}
+
+	return s.tokens.Revoke(token)
+}
+
+// Resume marks the client as connected again and returns its session, so a client
+// which lost its connection or restarted can continue where it left off
+func (s *ChatService) Resume(clientId string) (*ty.JsonSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	client, exists := s.clients[clientId]
+	if !exists || client.IsEvicted() {
+		return nil, fmt.Errorf("%w: there is no client with id: %s registered", ty.ErrNotAvailable, clientId)
+	}
+
+	client.SetDisconnected(false)
+	client.updateLastSign()
+
+	session := &ty.JsonSession{
+		ClientId:  clientId,
+		Name:      client.GetName(),
+		AuthToken: client.GetAuthToken(),
+	}
+
+	for _, group := range client.GetGroups() {
+		session.Groups = append(session.Groups, group.toJson())
+	}
+
+	group, exists := s.groups[client.GetGroupId()]
+	if exists {
+		session.Group = group.toJson()
+	}
+
+	fmt.Printf("\nclient %s resumed its session", clientId)
+
+	return session, nil
+}
+
+// DeliveryStats returns the delivery counters over every client
+func (s *ChatService) DeliveryStats() DeliveryInfo {
+	return s.stats.info()
 }
 
 func (s *ChatService) LogOutAllUsers() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+
+	err := s.saveGroupsRequireLock()
+	if err != nil {
+		fmt.Printf("\n%v", err)
+	}
+
 	for _, client := range s.clients {
-		client.Send(&ty.Response{Content: ty.UnregisterFlag})
+		client.Send(ty.UnregisteredEvent(""))
 	}
 }
 
-func (s *ChatService) ForwardSignal(msg *ty.Message, signal string) error {
+// ForwardSignal sends the signal content from ownId to the opposing client Message.ClientId
+// in the group Message.GroupId
+func (s *ChatService) ForwardSignal(ownId string, msg *ty.Message, signal string) error {
 	oppClient, err := s.GetClient(msg.ClientId)
 	if err != nil {
 		return err
 	}
 
-	oppClient.Send(&ty.Response{RspName: signal, ClientId: msg.Name, Content: msg.Content})
+	rsp := ty.SignalEvent(signal, ownId, msg.Content)
+	rsp.GroupId = msg.GroupId
+	oppClient.Send(rsp)
 
-	fmt.Printf("\n%s sent from %s -> %s", signal, msg.Name, msg.ClientId)
+	fmt.Printf("\n%s sent from %s -> %s", signal, ownId, msg.ClientId)
 	return nil
 }
 
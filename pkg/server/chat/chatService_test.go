@@ -0,0 +1,65 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// idleFor backdates the last sign of life of the client
+func idleFor(client *Client, d time.Duration) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	client.lastSign = time.Now().UTC().Add(-d)
+}
+
+func TestGracePeriod(t *testing.T) {
+	service, pr := newTestService(t, func(cfg *config.Config) {
+		cfg.TimeLimit = config.Duration(time.Minute)
+		cfg.GracePeriod = config.Duration(2 * time.Minute)
+	})
+	register(t, pr, ClientId, ClientName)
+
+	client, err := service.GetClient(ClientId)
+	assert.Nil(t, err)
+	drain(client)
+
+	// an idle client is only disconnected and queues its responses
+	idleFor(client, time.Minute+time.Second)
+	service.InactiveObjectDeleter()
+
+	_, err = service.GetClient(ClientId)
+	assert.Nil(t, err)
+	assert.True(t, client.IsDisconnected())
+	assert.Nil(t, client.Send(ty.NoticeEvent("while disconnected")))
+
+	session, err := service.Resume(ClientId)
+	assert.Nil(t, err)
+	assert.Equal(t, ClientName, session.Name)
+	assert.Equal(t, client.GetAuthToken(), session.AuthToken)
+	assert.False(t, client.IsDisconnected())
+
+	rsps := receive(t, client, 1)
+	if assert.Len(t, rsps, 1) {
+		assert.Equal(t, "while disconnected", rsps[0].Content)
+	}
+
+	// resuming restarted the time limit
+	service.InactiveObjectDeleter()
+	assert.False(t, client.IsDisconnected())
+
+	// after the grace period the session is gone
+	idleFor(client, 3*time.Minute+time.Second)
+	service.InactiveObjectDeleter()
+
+	_, err = service.GetClient(ClientId)
+	assert.ErrorIs(t, err, ty.ErrNotAvailable)
+
+	_, err = service.Resume(ClientId)
+	assert.ErrorIs(t, err, ty.ErrNotAvailable)
+}
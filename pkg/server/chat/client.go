@@ -3,6 +3,8 @@ package chat
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
@@ -10,7 +12,7 @@ import (
 )
 
 type PluginHandler interface {
-	FindAndExecute(message *ty.Message) (*ty.Response, error)
+	FindAndExecute(ctx context.Context, message *ty.Message) (*ty.Response, error)
 }
 
 // Client is a communication participant who has a name, unique id and
@@ -20,47 +22,160 @@ type Client struct {
 	ClientId      string `json:"clientId"`
 	GroupName     string `json:"groupName"`
 	clientCh      chan *ty.Response
+	priority      []*ty.Response
+	notify        chan struct{}
+	policy        DeliveryPolicy
+	stats         *DeliveryStats
+	dropped       int
+	evicted       bool
 	active        bool
 	authToken     string
 	lastSign      time.Time
 	mu            sync.RWMutex
 	chClosed      bool
+	disconnected  bool
 	groupId       string
+	groups        map[string]*Group
+	account       string
 	isNegotiating bool
 	// key represents opposing clientId and value the current callState
 	rtcs map[string]string
+	// sequence number of the last delivered response and the delivered responses which
+	// weren't acknowledged yet, they are redelivered to a cursor behind them
+	seq     uint64
+	unacked []*ty.Response
 }
 
-func (c *Client) Execute(handler PluginHandler, msg *ty.Message) (*ty.Response, error) {
+// maxUnacked is the number of delivered responses kept for redelivery
+const maxUnacked = 100
+
+// Execute executes the message with the handler, ctx has to carry the verified caller
+func (c *Client) Execute(ctx context.Context, handler PluginHandler, msg *ty.Message) (*ty.Response, error) {
 	c.setActive(true)
 	defer c.setActive(false)
 
 	defer c.updateLastSign()
 
-	return handler.FindAndExecute(msg)
+	return handler.FindAndExecute(ctx, msg)
 }
 
-// Receive receives responses from the clientCh
+// Receive receives the next response, priority responses first, and delivers it with the
+// next sequence number
 func (c *Client) Receive(ctx context.Context) (*ty.Response, error) {
 	c.setActive(true)
 	defer c.setActive(false)
 
 	defer c.updateLastSign()
 
-	select {
-	case rsp, ok := <-c.clientCh:
-		if !ok {
-			return nil, fmt.Errorf("%w: your channel was deleted, please register again", ty.ErrChannelClosed)
+	c.SetDisconnected(false)
+
+	for {
+		if rsp, ok := c.popPriority(); ok {
+			return c.deliver(rsp), nil
+		}
+
+		select {
+		case rsp, ok := <-c.clientCh:
+			if !ok {
+				return nil, fmt.Errorf("%w: your channel was deleted, please register again", ty.ErrChannelClosed)
+			}
+
+			c.resetDrops()
+			return c.deliver(rsp), nil
+
+		case <-c.notify:
+			// a priority response was queued
+
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: get request timed out", ty.ErrTimeoutReached)
+		}
+	}
+}
+
+// ReceiveBatch blocks until a response is received and then drains up to max-1
+// further queued responses without waiting for them
+func (c *Client) ReceiveBatch(ctx context.Context, max int) ([]*ty.Response, error) {
+	rsp, err := c.Receive(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rsps := []*ty.Response{rsp}
+
+	for len(rsps) < max {
+		if rsp, ok := c.popPriority(); ok {
+			rsps = append(rsps, c.deliver(rsp))
+			continue
+		}
+
+		select {
+		case rsp, ok := <-c.clientCh:
+			if !ok {
+				return rsps, nil
+			}
+
+			rsps = append(rsps, c.deliver(rsp))
+		default:
+			return rsps, nil
 		}
+	}
+
+	return rsps, nil
+}
+
+// deliver copies the response, since broadcasts share it between clients, assigns it the next
+// sequence number and keeps it until it is acknowledged
+func (c *Client) deliver(rsp *ty.Response) *ty.Response {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delivered := *rsp
+	if delivered.Time.IsZero() {
+		delivered.Time = time.Now().UTC()
+	}
 
-		return rsp, nil
+	c.seq++
+	delivered.Seq = c.seq
 
-	case <-ctx.Done():
-		return nil, fmt.Errorf("%w: get request timed out", ty.ErrTimeoutReached)
+	c.unacked = append(c.unacked, &delivered)
+	if len(c.unacked) > maxUnacked {
+		c.unacked = c.unacked[len(c.unacked)-maxUnacked:]
 	}
+
+	return &delivered
 }
 
-// Send sends a response to the clientCh
+// Ack acknowledges every delivered response up to seq, so it isn't redelivered anymore
+func (c *Client) Ack(seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := 0
+	for i < len(c.unacked) && c.unacked[i].Seq <= seq {
+		c.unacked[i] = nil
+		i++
+	}
+
+	c.unacked = c.unacked[i:]
+}
+
+// Unacked returns every delivered response after seq which wasn't acknowledged yet
+func (c *Client) Unacked(seq uint64) []*ty.Response {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var rsps []*ty.Response
+	for _, rsp := range c.unacked {
+		if rsp.Seq > seq {
+			rsps = append(rsps, rsp)
+		}
+	}
+
+	return rsps
+}
+
+// Send queues a response for the client, signaling and membership events are queued with
+// priority and never dropped, chat responses are handled by the DeliveryPolicy if the client is too slow
 func (c *Client) Send(rsp *ty.Response) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -69,26 +184,54 @@ func (c *Client) Send(rsp *ty.Response) error {
 		return fmt.Errorf("%w: your channel was deleted, please register again", ty.ErrChannelClosed)
 	}
 
-	select {
-	case c.clientCh <- rsp:
-		fmt.Printf("\n%s -> %s", rsp.RspName, c.Name)
-		return nil
-	default:
-		return fmt.Errorf("%w: response couldn't be sent, try again", ty.ErrTimeoutReached)
+	var err error
+	if isPriority(rsp) {
+		err = c.enqueuePriorityRequireLock(rsp)
+	} else {
+		err = c.enqueueChatRequireLock(rsp)
+	}
+
+	if err != nil {
+		return err
 	}
+
+	fmt.Printf("\n%s -> %s", rsp.RspName, c.Name)
+	return nil
 }
 
-// IsIdle checks if the client is inactive
+// resetDrops resets the drops counted for the eviction, since the client is receiving again
+func (c *Client) resetDrops() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dropped = 0
+}
+
+// Idle checks if the client has been inactive for at least the timeLimit
 func (c *Client) Idle(timeLimit time.Duration) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return !c.active && time.Since(c.lastSign) >= timeLimit
+}
+
+// SetDisconnected marks the client as (dis)connected and returns if the state changed.
+// A disconnected client keeps its channel, which queues responses until it resumes
+func (c *Client) SetDisconnected(disconnected bool) bool {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if !c.active && time.Since(c.lastSign) >= timeLimit {
-		c.closeChannelRequireLock()
-		return true
-	}
+	changed := c.disconnected != disconnected
+	c.disconnected = disconnected
 
-	return false
+	return changed
+}
+
+func (c *Client) IsDisconnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.disconnected
 }
 
 func (c *Client) RemoveUnconnectedRTCs() {
@@ -144,6 +287,18 @@ func (c *Client) GetAuthToken() string {
 	return c.authToken
 }
 
+// SetAuthToken replaces the token of the client and returns the old one
+func (c *Client) SetAuthToken(token string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	oldToken := c.authToken
+	c.authToken = token
+
+	return oldToken
+}
+
+// GetGroupId returns the id of the active group, it is empty if the lobby is active
 func (c *Client) GetGroupId() string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -151,12 +306,89 @@ func (c *Client) GetGroupId() string {
 	return c.groupId
 }
 
-func (c *Client) SetGroup(g *Group) {
+// AddGroup adds the group to the groups of the client and makes it the active one
+func (c *Client) AddGroup(g *Group) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.groups[g.GroupId] = g
+	c.groupId = g.GroupId
+	c.GroupName = g.Name
+}
+
+// SwitchGroup makes the group of the client the active one, the lobby if g is nil
+func (c *Client) SwitchGroup(g *Group) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if g == nil {
+		c.groupId = ""
+		c.GroupName = ""
+		return nil
+	}
+
+	if _, member := c.groups[g.GroupId]; !member {
+		return fmt.Errorf("%w: you are not in the group %s", ty.ErrNoPermission, g.Name)
+	}
+
 	c.groupId = g.GroupId
 	c.GroupName = g.Name
+
+	return nil
+}
+
+// RefreshGroupName takes over the name of the group if it is the active one
+func (c *Client) RefreshGroupName(g *Group) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.groupId == g.GroupId {
+		c.GroupName = g.Name
+	}
+}
+
+// RemoveGroup removes the group from the groups of the client, the lobby becomes
+// active if it was the active group
+func (c *Client) RemoveGroup(g *Group) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.groups, g.GroupId)
+
+	if c.groupId == g.GroupId {
+		c.groupId = ""
+		c.GroupName = ""
+	}
+}
+
+// GetGroup returns the group of the client with the groupId or the active group
+// if groupId is empty, it is nil if the lobby is active
+func (c *Client) GetGroup(groupId string) (*Group, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if groupId == "" {
+		groupId = c.groupId
+	}
+
+	if groupId == "" {
+		return nil, nil
+	}
+
+	group, member := c.groups[groupId]
+	if !member {
+		return nil, fmt.Errorf("%w: you are not in the group %s", ty.ErrNoPermission, groupId)
+	}
+
+	return group, nil
+}
+
+// GetGroups returns every group the client is a member of
+func (c *Client) GetGroups() []*Group {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return slices.Collect(maps.Values(c.groups))
 }
 
 func (c *Client) GetCallState(oppId string) string {
@@ -178,12 +410,21 @@ func (c *Client) SetCallState(oppId string, callState string) error {
 	return nil
 }
 
-func (c *Client) UnsetGroup() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+// GetLastSign returns the time of the last request of the client
+func (c *Client) GetLastSign() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-	c.groupId = ""
-	c.GroupName = ""
+	return c.lastSign
+}
+
+// GetAccount returns the name of the account the client is logged in with,
+// it is empty for guests registered with /register
+func (c *Client) GetAccount() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.account
 }
 
 func (c *Client) GetName() string {
@@ -193,6 +434,17 @@ func (c *Client) GetName() string {
 	return c.Name
 }
 
+// SetName renames the client and returns its old name
+func (c *Client) SetName(name string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	oldName := c.Name
+	c.Name = name
+
+	return oldName
+}
+
 func (c *Client) CheckRTC(oppId string) (string, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// DeliveryPolicy decides what happens to responses of a client which doesn't receive fast enough
+type DeliveryPolicy struct {
+	// config.DropOldest or config.DropNewest, applied to chat responses if the channel is full
+	ChatPolicy string
+	// maximum backlog of signaling and membership responses, which are never dropped
+	PriorityLimit int
+	// chat responses dropped since the last receive before the client is evicted, 0 disables it
+	DropLimit int
+}
+
+func newDeliveryPolicy(cfg *config.Config) DeliveryPolicy {
+	return DeliveryPolicy{
+		ChatPolicy:    cfg.ChatPolicy,
+		PriorityLimit: cfg.PriorityBuffer,
+		DropLimit:     cfg.SlowConsumerDrops,
+	}
+}
+
+// DeliveryStats counts the delivery of responses over every client
+type DeliveryStats struct {
+	queued      atomic.Uint64
+	prioritized atomic.Uint64
+	dropped     atomic.Uint64
+	evicted     atomic.Uint64
+}
+
+// DeliveryInfo is a snapshot of the DeliveryStats for server operators
+type DeliveryInfo struct {
+	Queued      uint64 `json:"queued"`
+	Prioritized uint64 `json:"prioritized"`
+	Dropped     uint64 `json:"dropped"`
+	Evicted     uint64 `json:"evicted"`
+}
+
+func (ds *DeliveryStats) info() DeliveryInfo {
+	return DeliveryInfo{
+		Queued:      ds.queued.Load(),
+		Prioritized: ds.prioritized.Load(),
+		Dropped:     ds.dropped.Load(),
+		Evicted:     ds.evicted.Load(),
+	}
+}
+
+// isPriority reports whether the response is a signaling or membership event, which mustn't be dropped
+func isPriority(rsp *ty.Response) bool {
+	switch rsp.Kind {
+	case ty.KindSignal, ty.KindUserJoined, ty.KindUserLeft, ty.KindUserRenamed, ty.KindGroupJoined, ty.KindGroupLeft,
+		ty.KindGroupSwitched, ty.KindGroupUpdated, ty.KindGroupInvite, ty.KindUnregistered:
+		return true
+	}
+
+	return false
+}
+
+// enqueuePriorityRequireLock appends the response to the priority queue and wakes up a waiting
+// receiver, the client is evicted if the backlog exceeds the PriorityLimit
+func (c *Client) enqueuePriorityRequireLock(rsp *ty.Response) error {
+	if len(c.priority) >= c.policy.PriorityLimit {
+		c.evictRequireLock(fmt.Sprintf("%d signaling and membership events are queued", len(c.priority)))
+		return fmt.Errorf("%w: client %s was evicted as slow consumer", ty.ErrChannelClosed, c.Name)
+	}
+
+	c.priority = append(c.priority, rsp)
+	c.stats.prioritized.Add(1)
+
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// enqueueChatRequireLock puts the response into the channel, if it is full the oldest or the
+// new response is dropped depending on the ChatPolicy. The client is evicted after DropLimit drops
+func (c *Client) enqueueChatRequireLock(rsp *ty.Response) error {
+	select {
+	case c.clientCh <- rsp:
+		c.stats.queued.Add(1)
+		return nil
+	default:
+	}
+
+	c.dropped++
+	c.stats.dropped.Add(1)
+
+	if c.policy.DropLimit > 0 && c.dropped >= c.policy.DropLimit {
+		c.evictRequireLock(fmt.Sprintf("%d chat responses were dropped", c.dropped))
+		return fmt.Errorf("%w: client %s was evicted as slow consumer", ty.ErrChannelClosed, c.Name)
+	}
+
+	if c.policy.ChatPolicy != config.DropOldest {
+		return fmt.Errorf("%w: response couldn't be sent, try again", ty.ErrTimeoutReached)
+	}
+
+	// the receiver may have emptied the channel in the meantime, so neither step can block
+	select {
+	case <-c.clientCh:
+	default:
+	}
+
+	select {
+	case c.clientCh <- rsp:
+		c.stats.queued.Add(1)
+	default:
+	}
+
+	return nil
+}
+
+// popPriority returns the oldest queued priority response
+func (c *Client) popPriority() (*ty.Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.priority) == 0 {
+		return nil, false
+	}
+
+	rsp := c.priority[0]
+	c.priority[0] = nil
+	c.priority = c.priority[1:]
+
+	return rsp, true
+}
+
+// evictRequireLock closes the channel of a hopelessly slow client, it is removed by the InactiveObjectDeleter
+func (c *Client) evictRequireLock(reason string) {
+	if c.evicted {
+		return
+	}
+
+	fmt.Printf("\nevicting slow consumer %s: %s", c.Name, reason)
+
+	c.evicted = true
+	c.priority = nil
+	c.stats.evicted.Add(1)
+	c.closeChannelRequireLock()
+}
+
+// IsEvicted reports whether the client was evicted as slow consumer
+func (c *Client) IsEvicted() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.evicted
+}
@@ -0,0 +1,110 @@
+package chat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// receive receives n responses of the client and fails the test if they don't arrive in time
+func receive(t *testing.T, client *Client, n int) []*ty.Response {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	rsps := []*ty.Response{}
+	for range n {
+		rsp, err := client.Receive(ctx)
+		if !assert.Nil(t, err) {
+			break
+		}
+
+		rsps = append(rsps, rsp)
+	}
+
+	return rsps
+}
+
+// drain receives and acknowledges every queued response, like the events of the registration
+func drain(client *Client) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		rsp, err := client.Receive(ctx)
+		cancel()
+
+		if err != nil {
+			return
+		}
+
+		client.Ack(rsp.Seq)
+	}
+}
+
+func TestDeliveryAcks(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+
+	client, err := service.GetClient(ClientId)
+	assert.Nil(t, err)
+
+	drain(client)
+
+	for _, content := range []string{"a", "b", "c"} {
+		assert.Nil(t, client.Send(ty.NoticeEvent(content)))
+	}
+
+	rsps := receive(t, client, 3)
+	assert.Len(t, rsps, 3)
+	assert.Less(t, rsps[0].Seq, rsps[1].Seq)
+	assert.Less(t, rsps[1].Seq, rsps[2].Seq)
+
+	// every received response stays unacknowledged until the cursor passed it
+	assert.Len(t, client.Unacked(0), 3)
+
+	client.Ack(rsps[1].Seq)
+	unacked := client.Unacked(0)
+	assert.Len(t, unacked, 1)
+	assert.Equal(t, rsps[2].Seq, unacked[0].Seq)
+	assert.Equal(t, rsps[2].Content, unacked[0].Content)
+
+	assert.Empty(t, client.Unacked(rsps[2].Seq))
+
+	client.Ack(rsps[2].Seq)
+	assert.Empty(t, client.Unacked(0))
+}
+
+func TestDeliveryKeepsLimitedUnacked(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+
+	client, err := service.GetClient(ClientId)
+	assert.Nil(t, err)
+
+	for range maxUnacked + 10 {
+		client.deliver(ty.NoticeEvent("x"))
+	}
+
+	unacked := client.Unacked(0)
+	assert.Len(t, unacked, maxUnacked)
+	assert.Equal(t, uint64(maxUnacked+10), unacked[len(unacked)-1].Seq)
+}
+
+func TestPriorityResponsesAreReceivedFirst(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+
+	client, err := service.GetClient(ClientId)
+	assert.Nil(t, err)
+
+	drain(client)
+
+	assert.Nil(t, client.Send(ty.NoticeEvent("chat")))
+	assert.Nil(t, client.Send(ty.UserLeftEvent(ClientId2, ClientName2)))
+
+	rsps := receive(t, client, 2)
+	assert.Equal(t, ty.KindUserLeft, rsps[0].Kind)
+	assert.Equal(t, ty.KindNotice, rsps[1].Kind)
+}
@@ -1,11 +1,14 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
-	"sync"
+
+	"golang.org/x/crypto/bcrypt"
 
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
@@ -30,13 +33,12 @@ func (ghp *GroupHelpPlugin) Description() *Description {
 	}
 }
 
-func (ghp *GroupHelpPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	jsonList, err := json.Marshal(ListPlugins(ghp.gpr.gPlugins))
-	if err != nil {
-		return nil, fmt.Errorf("%w: error parsing plugins to json", err)
-	}
+func (ghp *GroupHelpPlugin) CheckScope() int {
+	return RegisteredOnly
+}
 
-	return &ty.Response{RspName: "Group Help", Content: string(jsonList)}, nil
+func (ghp *GroupHelpPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	return ty.TableEvent("Group Help", ListPlugins(ghp.gpr.gPlugins)), nil
 }
 
 // GroupListPlugin
@@ -50,22 +52,28 @@ func NewGroupListPlugin(s *ChatService) *GroupListPlugin {
 
 func (glp *GroupListPlugin) Description() *Description {
 	return &Description{
-		Description: "lists every group plus info",
+		Description: "lists every public group plus info",
 		Template:    "/group list",
 	}
 }
 
-func (glp *GroupListPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (glp *GroupListPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (glp *GroupListPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
 	glp.s.mu.Lock()
 	defer glp.s.mu.Unlock()
 
-	if len(glp.s.groups) < 1 {
-		return &ty.Response{Err: fmt.Sprintf("%v: there are no groups", ty.ErrNotAvailable)}, nil
-	}
-
+	caller := CallerFrom(ctx)
 	groupSlice := []json.RawMessage{}
 
 	for _, group := range glp.s.groups {
+		// unlisted and private groups are only listed to their members
+		if group.Visibility != ty.PublicGroup && group.Role(caller.ClientId) == "" {
+			continue
+		}
+
 		group.SetSize()
 
 		jsonString, err := json.Marshal(group)
@@ -76,12 +84,7 @@ func (glp *GroupListPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
 		groupSlice = append(groupSlice, jsonString)
 	}
 
-	jsonList, err := json.Marshal(groupSlice)
-	if err != nil {
-		return nil, fmt.Errorf("%w: error parsing clients to json", err)
-	}
-
-	return &ty.Response{RspName: "Group List", Content: string(jsonList)}, nil
+	return ty.TableEvent("Group List", groupSlice), nil
 }
 
 // GroupCreatePlugin
@@ -95,50 +98,68 @@ func NewGroupCreatePlugin(s *ChatService) *GroupCreatePlugin {
 
 func (gcp *GroupCreatePlugin) Description() *Description {
 	return &Description{
-		Description: "creates a chat group",
-		Template:    "/group create {name}",
+		Description: "creates a chat group, which is public if no visibility is given",
+		Template:    "/group create {name} [--public|--unlisted|--private] [--password {password}]",
 	}
 }
 
-func (gcp *GroupCreatePlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (gcp *GroupCreatePlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (gcp *GroupCreatePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	name, visibility, password, err := ty.SplitGroupOptions(msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	name, err = parseGroupName(name)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	var passwordHash []byte
+	if password != "" {
+		if len(password) > maxPasswordLength {
+			return ty.ErrorEvent(fmt.Errorf("%w: the password can't be longer than %d chars", ty.ErrParsing, maxPasswordLength)), nil
+		}
+
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("%w: password couldn't be hashed", err)
+		}
+
+		passwordHash = hash
+	}
+
 	gcp.s.mu.Lock()
 	defer gcp.s.mu.Unlock()
 
-	name := strings.TrimSpace(msg.Content)
 	id := ty.GenerateSecureToken(32)
-	clients := make(map[string]*Client)
 
-	client, exists := gcp.s.clients[msg.ClientId]
+	caller := CallerFrom(ctx)
+
+	client, exists := gcp.s.clients[caller.ClientId]
 	if !exists {
-		return nil, fmt.Errorf("%w: there is no client with id: %s registered", ty.ErrNotAvailable, msg.ClientId)
+		return nil, fmt.Errorf("%w: there is no client with id: %s registered", ty.ErrNotAvailable, caller.ClientId)
 	}
 
-	clients[msg.ClientId] = client
-	group := &Group{GroupId: id, Name: name, clients: clients, mu: &sync.RWMutex{}, rtcs: make(map[string]bool)}
+	group := newGroup(id, name, visibility, passwordHash, client)
 	gcp.s.groups[id] = group
-	client.SetGroup(group)
+	client.AddGroup(group)
 
 	fmt.Printf("\nnew group %s created", group.Name)
 
-	jsonGroup, err := json.Marshal(group)
-	if err != nil {
-		return nil, fmt.Errorf("%w: error parsing group to json", err)
-	}
-
-	return &ty.Response{RspName: ty.AddGroupFlag, Content: string(jsonGroup)}, nil
+	return ty.GroupJoinedEvent(group.toJson()), nil
 }
 
 // GrouLeavePlugin
 type GroupLeavePlugin struct {
-	s  *ChatService
-	pr *PluginRegistry
+	s *ChatService
 }
 
-func NewGroupLeavePlugin(s *ChatService, pr *PluginRegistry) *GroupLeavePlugin {
-	return &GroupLeavePlugin{
-		s:  s,
-		pr: pr,
-	}
+func NewGroupLeavePlugin(s *ChatService) *GroupLeavePlugin {
+	return &GroupLeavePlugin{s: s}
 }
 
 func (glp *GroupLeavePlugin) Description() *Description {
@@ -148,28 +169,28 @@ func (glp *GroupLeavePlugin) Description() *Description {
 	}
 }
 
-func (glp *GroupLeavePlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	group, client, err := GetCurrentGroup(msg.ClientId, glp.s)
+func (glp *GroupLeavePlugin) CheckScope() int {
+	return InGroupOnly
+}
+
+func (glp *GroupLeavePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	group, client, err := GetTargetGroup(caller.ClientId, msg.GroupId, glp.s)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: error getting current group", err)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
 	}
 
 	if group == nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: you are not in a group", ty.ErrNotAvailable)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: you are not in a group", ty.ErrNotAvailable)), nil
 	}
 
-	err = group.RemoveClient(client)
+	err = leaveGroup(glp.s, group, client)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: error while removing client from group", err)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: error while removing client from group", err)), nil
 	}
 
-	group.RemoveConnection(msg.ClientId, "", true)
-
-	client.Execute(glp.pr, &ty.Message{Name: ty.UserRemoveFlag, Plugin: "/broadcast", Content: fmt.Sprintf("%s hat die Gruppe verlassen", msg.Name), ClientId: msg.ClientId, GroupId: msg.GroupId})
-
-	client.UnsetGroup()
-
-	return &ty.Response{RspName: ty.LeaveGroupFlag, Content: "Du hast die Gruppe verlassen"}, nil
+	return ty.GroupLeftEvent(group.toJson(), "Du hast die Gruppe verlassen"), nil
 }
 
 // GroupUserPlugin
@@ -188,101 +209,882 @@ func (gup *GroupUsersPlugin) Description() *Description {
 	}
 }
 
-func (gup *GroupUsersPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	group, err := gup.s.GetGroup(msg.GroupId)
+func (gup *GroupUsersPlugin) CheckScope() int {
+	return InGroupOnly
+}
+
+func (gup *GroupUsersPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	group, _, err := GetTargetGroup(caller.ClientId, msg.GroupId, gup.s)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: error finding group", err)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: error finding group", err)), nil
+	}
+
+	if group == nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: you are not in a group", ty.ErrNoPermission)), nil
 	}
 
 	group.mu.RLock()
 	defer group.mu.RUnlock()
 
-	if group == nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: you are not in a group", ty.ErrNoPermission)}, nil
+	users := ClientsToJsonSliceRequireLock(group.clients, caller.ClientId)
+	for _, user := range users {
+		user.Role = group.roleRequireLock(user.ClientId)
 	}
 
-	groupsSlice := ClientsToJsonSliceRequireLock(group.clients, msg.ClientId)
+	return ty.UsersEvent(users), nil
+}
+
+// GroupHistoryPlugin
+type GroupHistoryPlugin struct {
+	s *ChatService
+}
+
+func NewGroupHistoryPlugin(s *ChatService) *GroupHistoryPlugin {
+	return &GroupHistoryPlugin{s: s}
+}
+
+func (ghp *GroupHistoryPlugin) Description() *Description {
+	return &Description{
+		Description: "shows the last n messages of the group",
+		Template:    "/group history [n]",
+	}
+}
 
-	jsonList, err := json.Marshal(groupsSlice)
+func (ghp *GroupHistoryPlugin) CheckScope() int {
+	return InGroupOnly
+}
+
+func (ghp *GroupHistoryPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	n, err := parseHistoryCount(msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	group, _, err := GetTargetGroup(CallerFrom(ctx).ClientId, msg.GroupId, ghp.s)
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
+	}
+
+	if group == nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: you are not in a group", ty.ErrNoPermission)), nil
+	}
+
+	messages, err := ghp.s.History(n, func(m ty.JsonMessage) bool {
+		return m.Scope == ty.GroupScope && m.GroupId == group.GroupId
+	})
 	if err != nil {
-		return nil, fmt.Errorf("%w: error parsing groups to json", err)
+		return nil, fmt.Errorf("%w: error reading message history", err)
 	}
 
-	return &ty.Response{RspName: ty.UsersFlag, Content: string(jsonList)}, nil
+	return historyResponse(messages)
 }
 
 // GroupJoinPlugin
 type GroupJoinPlugin struct {
-	s  *ChatService
-	pr *PluginRegistry
+	s *ChatService
 }
 
-func NewGroupJoinPlugin(s *ChatService, pr *PluginRegistry) *GroupJoinPlugin {
-	return &GroupJoinPlugin{
-		s:  s,
-		pr: pr,
-	}
+func NewGroupJoinPlugin(s *ChatService) *GroupJoinPlugin {
+	return &GroupJoinPlugin{s: s}
 }
 
 func (gjp *GroupJoinPlugin) Description() *Description {
 	return &Description{
-		Description: "lets you join a group",
-		Template:    "/group join {groupId}",
+		Description: "lets you join a group, protected ones with their password and private ones with an invitation",
+		Template:    "/group join {name|groupId} [password]",
 	}
 }
 
-func (gjp *GroupJoinPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	newGroupId := strings.TrimSpace(msg.Content)
+func (gjp *GroupJoinPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (gjp *GroupJoinPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	fields := strings.Fields(msg.Content)
+	if len(fields) < 1 {
+		return ty.ErrorEvent(fmt.Errorf("%w: expected {name|groupId} [password]", ty.ErrParsing)), nil
+	}
+
+	caller := CallerFrom(ctx)
+
+	// group names may contain spaces, so the last field is only taken as password
+	// if the whole content doesn't address a group
+	password := ""
+	group, err := gjp.s.ResolveGroup(caller.ClientId, strings.Join(fields, " "))
+	if errors.Is(err, ty.ErrNotAvailable) && len(fields) > 1 {
+		password = fields[len(fields)-1]
+		group, err = gjp.s.ResolveGroup(caller.ClientId, strings.Join(fields[:len(fields)-1], " "))
+	}
+
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	newGroupId := group.GroupId
 
-	client, err := gjp.s.GetClient(msg.ClientId)
+	client, err := gjp.s.GetClient(caller.ClientId)
 	if err != nil {
 		return nil, fmt.Errorf("%w: client (probably) already deleted", ty.ErrNotAvailable)
 	}
 
-	group, err := gjp.s.GetGroup(newGroupId)
+	// a pending invitation or a role kept for the account replaces the password
+	_, err = gjp.s.TakeInvite(caller.ClientId, newGroupId)
+	if err != nil && !group.KeepsRole(client.GetAccount()) {
+		// private groups aren't revealed to clients without invitation
+		if group.Visibility == ty.PrivateGroup {
+			return ty.ErrorEvent(fmt.Errorf("%w: there is no group with id: %s registered", ty.ErrNotAvailable, newGroupId)), nil
+		}
+
+		err = group.CheckPassword(password)
+		if err != nil {
+			return ty.ErrorEvent(err), nil
+		}
+	}
+
+	return joinGroup(gjp.s, caller, group)
+}
+
+// joinGroup adds the caller to the given group, which becomes its active one, and announces it
+// in the group. The caller stays in its other groups
+func joinGroup(s *ChatService, caller Caller, group *Group) (*ty.Response, error) {
+	client, err := s.GetClient(caller.ClientId)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: error finding group with id %s", err, newGroupId)}, nil
+		return nil, fmt.Errorf("%w: client (probably) already deleted", ty.ErrNotAvailable)
 	}
 
-	if msg.GroupId != "" {
-		client.Execute(gjp.pr, &ty.Message{Name: "", Plugin: "/broadcast", Content: fmt.Sprintf("%s hat die Gruppe verlassen", msg.Name), ClientId: msg.ClientId, GroupId: msg.GroupId})
-		client.UnsetGroup()
-		group.RemoveClient(client)
-		group.RemoveConnection(msg.ClientId, "", true)
+	if group.IsBanned(client) {
+		return ty.ErrorEvent(fmt.Errorf("%w: you are banned from this group", ty.ErrNoPermission)), nil
+	}
+
+	if _, member := group.GetClients()[caller.ClientId]; member {
+		return ty.ErrorEvent(fmt.Errorf("%w: you are already in this group", ty.ErrNoPermission)), nil
 	}
 
 	err = group.AddClient(client)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: error while adding client to group", err)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: error while adding client to group", err)), nil
+	}
+
+	client.AddGroup(group)
+
+	broadcastNotice(s, group, ty.UserJoinedEvent(caller.ClientId, caller.Name))
+
+	return ty.GroupJoinedEvent(group.toJson()), nil
+}
+
+// GroupInvitePlugin
+type GroupInvitePlugin struct {
+	s *ChatService
+}
+
+func NewGroupInvitePlugin(s *ChatService) *GroupInvitePlugin {
+	return &GroupInvitePlugin{s: s}
+}
+
+func (gip *GroupInvitePlugin) Description() *Description {
+	return &Description{
+		Description: "invites someone to your group",
+		Template:    "/group invite {name|clientId}",
+	}
+}
+
+func (gip *GroupInvitePlugin) CheckScope() int {
+	return InGroupOnly
+}
+
+func (gip *GroupInvitePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	invitee, err := gip.s.ResolveClient(msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	inviteeId := invitee.ClientId
+	caller := CallerFrom(ctx)
+
+	group, _, err := GetTargetGroup(caller.ClientId, msg.GroupId, gip.s)
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
+	}
+
+	if group == nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: you are not in a group", ty.ErrNoPermission)), nil
+	}
+
+	invite, err := gip.s.Invite(group, caller, inviteeId)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	err = gip.s.Echo(inviteeId, ty.GroupInviteEvent(invite.toJson()))
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: invitation couldn't be delivered", err)), nil
+	}
+
+	fmt.Printf("\n%s invited %s into group %s", caller.ClientId, inviteeId, group.GroupId)
+
+	return ty.NoticeEvent("Die Einladung wurde verschickt"), nil
+}
+
+// GroupAcceptPlugin
+type GroupAcceptPlugin struct {
+	s *ChatService
+}
+
+func NewGroupAcceptPlugin(s *ChatService) *GroupAcceptPlugin {
+	return &GroupAcceptPlugin{s: s}
+}
+
+func (gap *GroupAcceptPlugin) Description() *Description {
+	return &Description{
+		Description: "accepts an invitation and joins the group, the newest one if no group is given",
+		Template:    "/group accept [groupId]",
+	}
+}
+
+func (gap *GroupAcceptPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (gap *GroupAcceptPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	invite, err := gap.s.TakeInvite(caller.ClientId, strings.TrimSpace(msg.Content))
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	rsp, err := joinGroup(gap.s, caller, invite.group)
+	if err != nil || rsp.Kind == ty.KindError {
+		return rsp, err
+	}
+
+	gap.s.Echo(invite.inviterId, ty.NoticeEvent(fmt.Sprintf("%s hat deine Einladung in die Gruppe %s angenommen", caller.Name, invite.group.Name)))
+
+	return rsp, nil
+}
+
+// GroupDeclinePlugin
+type GroupDeclinePlugin struct {
+	s *ChatService
+}
+
+func NewGroupDeclinePlugin(s *ChatService) *GroupDeclinePlugin {
+	return &GroupDeclinePlugin{s: s}
+}
+
+func (gdp *GroupDeclinePlugin) Description() *Description {
+	return &Description{
+		Description: "declines an invitation, the newest one if no group is given",
+		Template:    "/group decline [groupId]",
+	}
+}
+
+func (gdp *GroupDeclinePlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (gdp *GroupDeclinePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	invite, err := gdp.s.TakeInvite(caller.ClientId, strings.TrimSpace(msg.Content))
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	gdp.s.Echo(invite.inviterId, ty.NoticeEvent(fmt.Sprintf("%s hat deine Einladung in die Gruppe %s abgelehnt", caller.Name, invite.group.Name)))
+
+	return ty.NoticeEvent(fmt.Sprintf("Du hast die Einladung in die Gruppe %s abgelehnt", invite.group.Name)), nil
+}
+
+// GroupInvitesPlugin
+type GroupInvitesPlugin struct {
+	s *ChatService
+}
+
+func NewGroupInvitesPlugin(s *ChatService) *GroupInvitesPlugin {
+	return &GroupInvitesPlugin{s: s}
+}
+
+func (gip *GroupInvitesPlugin) Description() *Description {
+	return &Description{
+		Description: "lists your pending invitations",
+		Template:    "/group invites",
+	}
+}
+
+func (gip *GroupInvitesPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (gip *GroupInvitesPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	invites := gip.s.Invites(CallerFrom(ctx).ClientId)
+	inviteSlice := []json.RawMessage{}
+
+	for _, invite := range invites {
+		jsonString, err := json.Marshal(invite.toRow())
+		if err != nil {
+			log.Printf("error parsing invite into group %s to json", invite.group.Name)
+			continue
+		}
+
+		inviteSlice = append(inviteSlice, jsonString)
+	}
+
+	return ty.TableEvent("Group Invites", inviteSlice), nil
+}
+
+// GroupKickPlugin
+type GroupKickPlugin struct {
+	s *ChatService
+}
+
+func NewGroupKickPlugin(s *ChatService) *GroupKickPlugin {
+	return &GroupKickPlugin{s: s}
+}
+
+func (gkp *GroupKickPlugin) Description() *Description {
+	return &Description{
+		Description: "removes a member from the group",
+		Template:    "/group kick {name|clientId}",
+	}
+}
+
+func (gkp *GroupKickPlugin) CheckScope() int {
+	return GroupModeratorOnly
+}
+
+func (gkp *GroupKickPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	group, member, err := groupMember(gkp.s, caller, msg.GroupId, msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	err = kickMember(gkp.s, group, caller, member, "entfernt")
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	return groupNotice(gkp.s, group, caller.ClientId, fmt.Sprintf("%s wurde von %s aus der Gruppe entfernt", member.GetName(), caller.Name)), nil
+}
+
+// kickMember removes the member from the group if the caller outranks it and tells the member why
+func kickMember(s *ChatService, group *Group, caller Caller, member *Client, action string) error {
+	if !outranks(group.Role(caller.ClientId), group.Role(member.ClientId)) {
+		return fmt.Errorf("%w: you can only moderate members with a lower role", ty.ErrNoPermission)
+	}
+
+	err := leaveGroup(s, group, member)
+	if err != nil {
+		return err
+	}
+
+	member.Send(ty.GroupLeftEvent(group.toJson(), fmt.Sprintf("Du wurdest von %s aus der Gruppe %s %s", caller.Name, group.Name, action)))
+
+	fmt.Printf("\n%s %s from group %s by %s", member.ClientId, action, group.GroupId, caller.ClientId)
+
+	return nil
+}
+
+// GroupBanPlugin
+type GroupBanPlugin struct {
+	s *ChatService
+}
+
+func NewGroupBanPlugin(s *ChatService) *GroupBanPlugin {
+	return &GroupBanPlugin{s: s}
+}
+
+func (gbp *GroupBanPlugin) Description() *Description {
+	return &Description{
+		Description: "removes someone from the group and prevents joining again",
+		Template:    "/group ban {name|clientId}",
+	}
+}
+
+func (gbp *GroupBanPlugin) CheckScope() int {
+	return GroupModeratorOnly
+}
+
+func (gbp *GroupBanPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	group, target, err := banTarget(gbp.s, caller, msg.GroupId, msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
 	}
 
-	client.SetGroup(group)
+	// absent clients are ranked by the role the group keeps for their account
+	if !outranks(group.Role(caller.ClientId), group.RoleOrKept(target.ClientId, target.GetAccount())) {
+		return ty.ErrorEvent(fmt.Errorf("%w: you can only moderate members with a lower role", ty.ErrNoPermission)), nil
+	}
+
+	if _, member := group.GetClients()[target.ClientId]; member {
+		err = kickMember(gbp.s, group, caller, target, "gebannt")
+		if err != nil {
+			return ty.ErrorEvent(err), nil
+		}
+	}
+
+	err = gbp.s.SetGroupBanned(group, target.ClientId, target.GetAccount(), true)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	return groupNotice(gbp.s, group, caller.ClientId, fmt.Sprintf("%s wurde von %s aus der Gruppe gebannt", target.GetName(), caller.Name)), nil
+}
+
+// banTarget returns the group of the caller and the client addressed by content, which is
+// looked up among the members first and then among every client, since absent clients can be banned as well
+func banTarget(s *ChatService, caller Caller, groupId string, content string) (*Group, *Client, error) {
+	group, member, err := groupMember(s, caller, groupId, content)
+	if err == nil || group == nil || !errors.Is(err, ty.ErrNotAvailable) {
+		return group, member, err
+	}
+
+	client, err := s.ResolveClient(content)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if client.ClientId == caller.ClientId {
+		return nil, nil, fmt.Errorf("%w: you can't use this command on yourself", ty.ErrNoPermission)
+	}
+
+	return group, client, nil
+}
+
+// GroupUnbanPlugin
+type GroupUnbanPlugin struct {
+	s *ChatService
+}
+
+func NewGroupUnbanPlugin(s *ChatService) *GroupUnbanPlugin {
+	return &GroupUnbanPlugin{s: s}
+}
+
+func (gup *GroupUnbanPlugin) Description() *Description {
+	return &Description{
+		Description: "allows a banned client to join the group again",
+		Template:    "/group unban {name|clientId}",
+	}
+}
+
+func (gup *GroupUnbanPlugin) CheckScope() int {
+	return GroupModeratorOnly
+}
+
+func (gup *GroupUnbanPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	target := strings.TrimSpace(msg.Content)
+	if target == "" {
+		return ty.ErrorEvent(fmt.Errorf("%w: expected {name|clientId}", ty.ErrParsing)), nil
+	}
+
+	group, _, err := GetTargetGroup(caller.ClientId, msg.GroupId, gup.s)
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
+	}
+
+	if group == nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: you are not in a group", ty.ErrNoPermission)), nil
+	}
+
+	// banned clients which aren't registered anymore are addressed by their id or account
+	bannedId, account := target, target
+	client, err := gup.s.ResolveClient(target)
+	switch {
+	case err == nil:
+		bannedId, account = client.ClientId, client.GetAccount()
+	case !errors.Is(err, ty.ErrNotAvailable):
+		return ty.ErrorEvent(err), nil
+	}
+
+	err = gup.s.SetGroupBanned(group, bannedId, account, false)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	return ty.NoticeEvent(fmt.Sprintf("%s ist nicht mehr aus der Gruppe gebannt", target)), nil
+}
+
+// GroupPromotePlugin
+type GroupPromotePlugin struct {
+	s *ChatService
+}
+
+func NewGroupPromotePlugin(s *ChatService) *GroupPromotePlugin {
+	return &GroupPromotePlugin{s: s}
+}
+
+func (gpp *GroupPromotePlugin) Description() *Description {
+	return &Description{
+		Description: "makes a member moderator of the group",
+		Template:    "/group promote {name|clientId}",
+	}
+}
+
+func (gpp *GroupPromotePlugin) CheckScope() int {
+	return GroupOwnerOnly
+}
+
+func (gpp *GroupPromotePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	return setModerator(gpp.s, CallerFrom(ctx), msg.GroupId, msg.Content, true)
+}
+
+// GroupDemotePlugin
+type GroupDemotePlugin struct {
+	s *ChatService
+}
+
+func NewGroupDemotePlugin(s *ChatService) *GroupDemotePlugin {
+	return &GroupDemotePlugin{s: s}
+}
+
+func (gdp *GroupDemotePlugin) Description() *Description {
+	return &Description{
+		Description: "makes a moderator member of the group again",
+		Template:    "/group demote {name|clientId}",
+	}
+}
+
+func (gdp *GroupDemotePlugin) CheckScope() int {
+	return GroupOwnerOnly
+}
+
+func (gdp *GroupDemotePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	return setModerator(gdp.s, CallerFrom(ctx), msg.GroupId, msg.Content, false)
+}
+
+// setModerator promotes or demotes the member addressed by content in the group addressed by
+// groupId and announces it in the group
+func setModerator(s *ChatService, caller Caller, groupId string, content string, moderator bool) (*ty.Response, error) {
+	group, member, err := groupMember(s, caller, groupId, content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	err = group.SetModerator(member.ClientId, moderator)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	role := "Moderator"
+	if !moderator {
+		role = "kein Moderator mehr"
+	}
+
+	return groupNotice(s, group, caller.ClientId, fmt.Sprintf("%s ist nun %s der Gruppe", member.GetName(), role)), nil
+}
+
+// GroupTransferPlugin
+type GroupTransferPlugin struct {
+	s *ChatService
+}
+
+func NewGroupTransferPlugin(s *ChatService) *GroupTransferPlugin {
+	return &GroupTransferPlugin{s: s}
+}
+
+func (gtp *GroupTransferPlugin) Description() *Description {
+	return &Description{
+		Description: "passes the ownership of the group to a member, you become moderator",
+		Template:    "/group transfer {name|clientId}",
+	}
+}
+
+func (gtp *GroupTransferPlugin) CheckScope() int {
+	return GroupOwnerOnly
+}
+
+func (gtp *GroupTransferPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+
+	group, member, err := groupMember(gtp.s, caller, msg.GroupId, msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	// persistent groups keep their owner by account
+	if group.IsPersistent() && member.GetAccount() == "" {
+		return ty.ErrorEvent(fmt.Errorf("%w: persistent groups can only be passed to members with an account", ty.ErrNoPermission)), nil
+	}
+
+	err = group.TransferOwnership(member.ClientId)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	fmt.Printf("\nownership of group %s passed to %s", group.GroupId, member.ClientId)
+
+	return groupNotice(gtp.s, group, caller.ClientId, fmt.Sprintf("%s ist nun Besitzer der Gruppe %s", member.GetName(), group.Name)), nil
+}
+
+// GroupPersistPlugin
+type GroupPersistPlugin struct {
+	s *ChatService
+}
+
+func NewGroupPersistPlugin(s *ChatService) *GroupPersistPlugin {
+	return &GroupPersistPlugin{s: s}
+}
+
+func (gpp *GroupPersistPlugin) Description() *Description {
+	return &Description{
+		Description: "keeps the group while it is empty and across restarts, off makes it an ad-hoc group again",
+		Template:    "/group persist [on|off]",
+	}
+}
+
+func (gpp *GroupPersistPlugin) CheckScope() int {
+	return GroupOwnerOnly
+}
+
+func (gpp *GroupPersistPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	var persistent bool
 
-	client.Execute(gjp.pr, &ty.Message{Name: ty.UserAddFlag, Plugin: "/broadcast", Content: msg.Name, ClientId: msg.ClientId, GroupId: newGroupId})
+	switch strings.TrimSpace(msg.Content) {
+	case "", "on":
+		persistent = true
+	case "off":
+		persistent = false
+	default:
+		return ty.ErrorEvent(fmt.Errorf("%w: expected on or off", ty.ErrParsing)), nil
+	}
+
+	caller := CallerFrom(ctx)
 
-	jsonGroup, err := json.Marshal(group)
+	group, client, err := GetTargetGroup(caller.ClientId, msg.GroupId, gpp.s)
 	if err != nil {
-		return nil, fmt.Errorf("%w: error parsing group to json", err)
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
+	}
+
+	// the group keeps its owner by account, without account anybody could take it over after a restart
+	if persistent && client.GetAccount() == "" {
+		return ty.ErrorEvent(fmt.Errorf("%w: log in with an account to keep the group", ty.ErrNoPermission)), nil
 	}
 
-	return &ty.Response{RspName: ty.AddGroupFlag, Content: string(jsonGroup)}, nil
+	err = gpp.s.SetGroupPersistent(group.GroupId, persistent)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	if persistent {
+		return groupNotice(gpp.s, group, caller.ClientId, fmt.Sprintf("Die Gruppe %s bleibt nun auch leer bestehen", group.Name)), nil
+	}
+
+	return groupNotice(gpp.s, group, caller.ClientId, fmt.Sprintf("Die Gruppe %s wird nun gelöscht, sobald sie leer ist", group.Name)), nil
 }
 
-// type GroupInvitePlugin struct {
-// 	s *ChatService
-// }
+// GroupSwitchPlugin
+type GroupSwitchPlugin struct {
+	s *ChatService
+}
 
-// func NewGroupInvitePlugin(s *ChatService) *GroupInvitePlugin {
-// 	return &GroupInvitePlugin{s: s}
-// }
+func NewGroupSwitchPlugin(s *ChatService) *GroupSwitchPlugin {
+	return &GroupSwitchPlugin{s: s}
+}
+
+func (gsp *GroupSwitchPlugin) Description() *Description {
+	return &Description{
+		Description: "makes one of your groups the active room, the lobby if no group is given",
+		Template:    "/group switch [name|groupId]",
+	}
+}
+
+func (gsp *GroupSwitchPlugin) CheckScope() int {
+	return RegisteredOnly
+}
 
-// func (gip *GroupInvitePlugin) Description() *Description {
-// 	return &Description{
-// 		Description: "invites someone to your group",
-// 		Template:    "/group invite {clientId}",
-// 	}
-// }
+func (gsp *GroupSwitchPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	client, err := gsp.s.GetClient(CallerFrom(ctx).ClientId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: client (probably) already deleted", ty.ErrNotAvailable)
+	}
+
+	target := strings.TrimSpace(msg.Content)
+	if target == "" {
+		client.SwitchGroup(nil)
+		return ty.GroupSwitchedEvent(nil), nil
+	}
+
+	var group *Group
+	for _, g := range client.GetGroups() {
+		if g.GroupId == target {
+			group = g
+			break
+		}
 
-// func (gip *GroupInvitePlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+		if strings.EqualFold(g.Name, target) {
+			if group != nil {
+				return ty.ErrorEvent(fmt.Errorf("%w: you are in several groups named %s, use the groupId", ty.ErrParsing, target)), nil
+			}
 
-// }
+			group = g
+		}
+	}
+
+	if group == nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: you are not in a group named %s", ty.ErrNotAvailable, target)), nil
+	}
+
+	err = client.SwitchGroup(group)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	return ty.GroupSwitchedEvent(group.toJson()), nil
+}
+
+// maximum lengths of group names and topics
+const maxGroupNameLength = 50
+const maxTopicLength = 200
+
+// parseGroupName collapses the whitespace of the group name and returns an error if
+// it is empty or too long
+func parseGroupName(content string) (string, error) {
+	name := strings.Join(strings.Fields(content), " ")
+	if name == "" || len(name) > maxGroupNameLength {
+		return "", fmt.Errorf("%w: the name has to be between 1 and %d chars long", ty.ErrParsing, maxGroupNameLength)
+	}
+
+	return name, nil
+}
+
+// GroupTopicPlugin
+type GroupTopicPlugin struct {
+	s *ChatService
+}
+
+func NewGroupTopicPlugin(s *ChatService) *GroupTopicPlugin {
+	return &GroupTopicPlugin{s: s}
+}
+
+func (gtp *GroupTopicPlugin) Description() *Description {
+	return &Description{
+		Description: "sets the topic of the group, without text the topic is removed",
+		Template:    "/group topic [text]",
+	}
+}
+
+func (gtp *GroupTopicPlugin) CheckScope() int {
+	return GroupModeratorOnly
+}
+
+func (gtp *GroupTopicPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	topic := strings.TrimSpace(msg.Content)
+	if len(topic) > maxTopicLength {
+		return ty.ErrorEvent(fmt.Errorf("%w: the topic can't be longer than %d chars", ty.ErrParsing, maxTopicLength)), nil
+	}
+
+	caller := CallerFrom(ctx)
+
+	group, _, err := GetTargetGroup(caller.ClientId, msg.GroupId, gtp.s)
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
+	}
+
+	err = gtp.s.SetGroupTopic(group, topic)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	if topic == "" {
+		return groupUpdate(gtp.s, group, caller.ClientId, fmt.Sprintf("%s hat das Thema der Gruppe entfernt", caller.Name)), nil
+	}
+
+	return groupUpdate(gtp.s, group, caller.ClientId, fmt.Sprintf("%s hat das Thema der Gruppe geändert: %s", caller.Name, topic)), nil
+}
+
+// GroupRenamePlugin
+type GroupRenamePlugin struct {
+	s *ChatService
+}
+
+func NewGroupRenamePlugin(s *ChatService) *GroupRenamePlugin {
+	return &GroupRenamePlugin{s: s}
+}
+
+func (grp *GroupRenamePlugin) Description() *Description {
+	return &Description{
+		Description: "renames the group",
+		Template:    "/group rename {name}",
+	}
+}
+
+func (grp *GroupRenamePlugin) CheckScope() int {
+	return GroupOwnerOnly
+}
+
+func (grp *GroupRenamePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	name, err := parseGroupName(msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	caller := CallerFrom(ctx)
+
+	group, _, err := GetTargetGroup(caller.ClientId, msg.GroupId, grp.s)
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
+	}
+
+	oldName := group.Name
+
+	err = grp.s.RenameGroup(group, name)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	return groupUpdate(grp.s, group, caller.ClientId, fmt.Sprintf("%s hat die Gruppe %s in %s umbenannt", caller.Name, oldName, name)), nil
+}
+
+// GroupInfoPlugin
+type GroupInfoPlugin struct {
+	s *ChatService
+}
+
+func NewGroupInfoPlugin(s *ChatService) *GroupInfoPlugin {
+	return &GroupInfoPlugin{s: s}
+}
+
+func (gip *GroupInfoPlugin) Description() *Description {
+	return &Description{
+		Description: "shows the topic, owner, moderators and settings of the group",
+		Template:    "/group info",
+	}
+}
+
+func (gip *GroupInfoPlugin) CheckScope() int {
+	return InGroupOnly
+}
+
+// groupInfoRow is a group as row of the /group info table
+type groupInfoRow struct {
+	Name       string `json:"name"`
+	GroupId    string `json:"groupId"`
+	Topic      string `json:"topic"`
+	Visibility string `json:"visibility"`
+	Owner      string `json:"owner"`
+	Moderators string `json:"moderators"`
+	Size       int    `json:"size"`
+	Persistent bool   `json:"persistent"`
+}
+
+func (gip *GroupInfoPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	group, _, err := GetTargetGroup(CallerFrom(ctx).ClientId, msg.GroupId, gip.s)
+	if err != nil {
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
+	}
+
+	row, err := json.Marshal(group.infoRow())
+	if err != nil {
+		return nil, fmt.Errorf("%w: error parsing group info to json", err)
+	}
+
+	return ty.TableEvent("Group Info", []json.RawMessage{row}), nil
+}
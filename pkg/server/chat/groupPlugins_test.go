@@ -0,0 +1,133 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestGroupBanByName(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+	register(t, pr, ClientId3, ClientName3)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+	group := groupNamed(t, service, "Room")
+
+	// members are kicked and clients outside of the group can be banned as well
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "ban "+ClientName2).Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "ban @"+ClientName3).Error)
+	assert.False(t, isMember(group, ClientId2))
+
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "join Room").Error, ty.ErrNoPermission)
+	assert.ErrorIs(t, execute(t, pr, ClientId3, "/group", "join Room").Error, ty.ErrNoPermission)
+
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "ban Nobody").Error, ty.ErrNotAvailable)
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "ban "+ClientName).Error, ty.ErrNoPermission)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "unban "+ClientName2).Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "unban "+ClientId3).Error)
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "unban "+ClientName2).Error, ty.ErrNotAvailable)
+
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Room").Error)
+}
+
+func TestGroupBanAccount(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	assert.Nil(t, execute(t, pr, ClientId2, "/signup", ClientName2+" "+Password).Error)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+	group := groupNamed(t, service, "Room")
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "ban "+ClientName2).Error)
+
+	// the ban sticks to the account instead of the clientId
+	execute(t, pr, ClientId2, "/quit", "")
+	assert.Nil(t, execute(t, pr, ClientId3, "/login", ClientName2+" "+Password).Error)
+	assert.ErrorIs(t, execute(t, pr, ClientId3, "/group", "join Room").Error, ty.ErrNoPermission)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "unban "+ClientName2).Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Room").Error)
+	assert.True(t, isMember(group, ClientId3))
+}
+
+func TestModeratorCantBanAbsentOwner(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	assert.Nil(t, execute(t, pr, ClientId, "/signup", ClientName+" "+Password).Error)
+	register(t, pr, ClientId2, ClientName2)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "persist on").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "promote "+ClientName2).Error)
+
+	// the persistent group keeps the owner role for the account while it is away
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "leave").Error)
+	assert.True(t, groupNamed(t, service, "Room").KeepsRole(ClientName))
+
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "ban "+ClientName).Error, ty.ErrNoPermission)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "join Room").Error)
+}
+
+func TestGroupBansArePersisted(t *testing.T) {
+	path := t.TempDir() + "/groups.json"
+
+	store, err := NewGroupStore(path)
+	assert.Nil(t, err)
+
+	service, pr := newTestService(t, nil)
+	service.RestoreGroups(store)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/signup", ClientName+" "+Password).Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/signup", ClientName2+" "+Password).Error)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "persist on").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "ban "+ClientName2).Error)
+
+	// a restarted server restores the ban of the account
+	store, err = NewGroupStore(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"len"}, store.Records()[0].Banned)
+
+	service, pr = newTestService(t, nil)
+	service.RestoreGroups(store)
+
+	assert.Nil(t, execute(t, pr, ClientId2, "/signup", ClientName2+" "+Password).Error)
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "join Room").Error, ty.ErrNoPermission)
+}
+
+func TestGroupNames(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	register(t, pr, ClientId, ClientName)
+
+	tooLong := strings.Repeat("x", maxGroupNameLength+1)
+
+	// create and rename share the validation of the name
+	for _, plugin := range []string{"create", "rename"} {
+		rsp := execute(t, pr, ClientId, "/group", plugin+" ")
+		assert.ErrorIs(t, rsp.Error, ty.ErrParsing)
+
+		rsp = execute(t, pr, ClientId, "/group", plugin+" "+tooLong)
+		assert.ErrorIs(t, rsp.Error, ty.ErrParsing)
+
+		rsp = execute(t, pr, ClientId, "/group", plugin+" My  "+plugin+" Room ")
+		assert.Nil(t, rsp.Error)
+	}
+
+	// a group needs a name besides its options
+	rsp := execute(t, pr, ClientId, "/group", "create --private")
+	assert.ErrorIs(t, rsp.Error, ty.ErrParsing)
+
+	group := groupNamed(t, service, "My rename Room")
+	assert.Equal(t, "My rename Room", group.toJson().Name)
+}
@@ -1,11 +1,16 @@
 package chat
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
 
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
@@ -16,22 +21,109 @@ type Group struct {
 	rtcs    map[string]bool
 	GroupId string `json:"groupId"`
 	Name    string `json:"name"`
-	mu      *sync.RWMutex
-	Size    int `json:"size"`
+	// ty.PublicGroup, ty.UnlistedGroup or ty.PrivateGroup
+	Visibility string `json:"visibility"`
+	// set if joining requires the password of the hash
+	Protected    bool `json:"protected"`
+	passwordHash []byte
+	ownerId      string
+	// clientIds of the moderators and of the banned clients
+	moderators map[string]bool
+	banned     map[string]bool
+	// time every member joined, the longest member becomes owner if the owner leaves
+	joined map[string]time.Time
+	mu     *sync.RWMutex
+	Size   int `json:"size"`
+	// set if the group is kept while empty and saved in the group store
+	Persistent bool `json:"persistent"`
+	// topic or description of the group
+	Topic string `json:"topic"`
+	// roles of lowercased account names whose members left a persistent group,
+	// members logged in with the account get them back when they join again
+	accountRoles map[string]string
+	// lowercased account names of banned clients, so they stay banned with another clientId
+	accountBans map[string]bool
+}
+
+// newGroup creates a group with the owner as only member, it is protected if a password hash is given
+func newGroup(groupId string, name string, visibility string, passwordHash []byte, owner *Client) *Group {
+	group := emptyGroup(groupId, name, visibility, passwordHash)
+	group.ownerId = owner.ClientId
+	group.clients[owner.ClientId] = owner
+	group.joined[owner.ClientId] = time.Now()
+
+	return group
+}
+
+// groupFromRecord creates the persistent group of the record without members, its owner
+// and moderators get their role when they join
+func groupFromRecord(record *GroupRecord) *Group {
+	group := emptyGroup(record.GroupId, record.Name, record.Visibility, record.PasswordHash)
+	group.Persistent = true
+	group.Topic = record.Topic
+
+	for _, account := range record.Moderators {
+		group.accountRoles[strings.ToLower(account)] = ty.ModeratorRole
+	}
+
+	if record.Owner != "" {
+		group.accountRoles[strings.ToLower(record.Owner)] = ty.OwnerRole
+	}
+
+	for _, account := range record.Banned {
+		group.accountBans[strings.ToLower(account)] = true
+	}
+
+	return group
+}
+
+func emptyGroup(groupId string, name string, visibility string, passwordHash []byte) *Group {
+	return &Group{
+		GroupId:      groupId,
+		Name:         name,
+		Visibility:   visibility,
+		Protected:    len(passwordHash) > 0,
+		passwordHash: passwordHash,
+		clients:      make(map[string]*Client),
+		rtcs:         make(map[string]bool),
+		moderators:   make(map[string]bool),
+		banned:       make(map[string]bool),
+		joined:       make(map[string]time.Time),
+		accountRoles: make(map[string]string),
+		accountBans:  make(map[string]bool),
+		mu:           &sync.RWMutex{},
+	}
 }
 
 type GroupPluginRegistry struct {
 	gPlugins map[string]PluginInterface
+	s        *ChatService
 }
 
-func RegisterGroupPlugins(s *ChatService, pr *PluginRegistry) *GroupPluginRegistry {
-	gp := &GroupPluginRegistry{gPlugins: make(map[string]PluginInterface)}
+func RegisterGroupPlugins(s *ChatService) *GroupPluginRegistry {
+	gp := &GroupPluginRegistry{gPlugins: make(map[string]PluginInterface), s: s}
 	gp.gPlugins["help"] = NewGroupHelpPlugin(s, gp)
 	gp.gPlugins["list"] = NewGroupListPlugin(s)
-	gp.gPlugins["join"] = NewGroupJoinPlugin(s, pr)
+	gp.gPlugins["join"] = NewGroupJoinPlugin(s)
 	gp.gPlugins["create"] = NewGroupCreatePlugin(s)
-	gp.gPlugins["leave"] = NewGroupLeavePlugin(s, pr)
+	gp.gPlugins["leave"] = NewGroupLeavePlugin(s)
 	gp.gPlugins["users"] = NewGroupUsersPlugin(s)
+	gp.gPlugins["history"] = NewGroupHistoryPlugin(s)
+	gp.gPlugins["invite"] = NewGroupInvitePlugin(s)
+	gp.gPlugins["invites"] = NewGroupInvitesPlugin(s)
+	gp.gPlugins["accept"] = NewGroupAcceptPlugin(s)
+	gp.gPlugins["decline"] = NewGroupDeclinePlugin(s)
+	gp.gPlugins["kick"] = NewGroupKickPlugin(s)
+	gp.gPlugins["ban"] = NewGroupBanPlugin(s)
+	gp.gPlugins["unban"] = NewGroupUnbanPlugin(s)
+	gp.gPlugins["promote"] = NewGroupPromotePlugin(s)
+	gp.gPlugins["demote"] = NewGroupDemotePlugin(s)
+	gp.gPlugins["transfer"] = NewGroupTransferPlugin(s)
+	gp.gPlugins["persist"] = NewGroupPersistPlugin(s)
+	gp.gPlugins["switch"] = NewGroupSwitchPlugin(s)
+	gp.gPlugins["topic"] = NewGroupTopicPlugin(s)
+	gp.gPlugins["rename"] = NewGroupRenamePlugin(s)
+	gp.gPlugins["info"] = NewGroupInfoPlugin(s)
 
 	return gp
 }
@@ -40,42 +132,137 @@ func (gp *GroupPluginRegistry) Description() *Description {
 	return gp.gPlugins["help"].Description()
 }
 
-func (gp *GroupPluginRegistry) Execute(message *ty.Message) (*ty.Response, error) {
+// CheckScope returns the scope required for every group command, the commands can require more
+func (gp *GroupPluginRegistry) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (gp *GroupPluginRegistry) Execute(ctx context.Context, message *ty.Message) (*ty.Response, error) {
 	newMsg, err := extractIdentifierMessage(message)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: no empty identifier allowed", err)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: no empty identifier allowed", err)), nil
 	}
 
 	plugin, ok := gp.gPlugins[newMsg.Plugin]
 	if !ok {
-		return &ty.Response{Err: fmt.Sprintf("%v: no such group command identifier found: %s", ty.ErrNoPermission, newMsg.Plugin)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: no such group command identifier found: %s", ty.ErrNoPermission, newMsg.Plugin)), nil
 	}
 
-	return plugin.Execute(message)
+	err = checkScope(ctx, gp.s, fmt.Sprintf("/group %s", newMsg.Plugin), plugin.CheckScope(), newMsg.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.Execute(ctx, message)
 }
 
 func (g *Group) AddClient(client *Client) error {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	if g.isBannedRequireLock(client) {
+		return fmt.Errorf("%w: you are banned from this group", ty.ErrNoPermission)
+	}
+
 	if _, exists := g.clients[client.ClientId]; !exists && client != nil {
 		g.clients[client.ClientId] = client
+		g.joined[client.ClientId] = time.Now()
+		g.restoreRoleRequireLock(client)
 		return nil
 	}
 
 	return fmt.Errorf("%w: you are already in this group", ty.ErrNoPermission)
 }
 
-func (g *Group) RemoveClient(client *Client) error {
+// restoreRoleRequireLock gives the member the role of its account back. An ad-hoc group without
+// owner is taken over by the member, persistent groups only get their owner account back
+func (g *Group) restoreRoleRequireLock(client *Client) {
+	account := strings.ToLower(client.GetAccount())
+	role := g.accountRoles[account]
+	delete(g.accountRoles, account)
+
+	switch {
+	case role == ty.OwnerRole && g.ownerId == "":
+		g.ownerId = client.ClientId
+	case role != "":
+		g.moderators[client.ClientId] = true
+	case g.ownerId == "" && !g.Persistent:
+		g.ownerId = client.ClientId
+	}
+}
+
+// RoleOrKept returns the role of the member or, if the client isn't a member, the role the
+// persistent group keeps for its account
+func (g *Group) RoleOrKept(clientId string, account string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if role := g.roleRequireLock(clientId); role != "" {
+		return role
+	}
+
+	return g.accountRoles[strings.ToLower(account)]
+}
+
+// KeepsRole reports whether the persistent group keeps a role for the account of a member who left
+func (g *Group) KeepsRole(account string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	_, exists := g.accountRoles[strings.ToLower(account)]
+	return exists
+}
+
+// RemoveClient removes the client and its role from the group. If the client was the owner, the ownership
+// is transferred to the longest moderator or else the longest member, whose id is returned. Persistent
+// groups keep the role of members with an account instead, so the owner stays owner
+func (g *Group) RemoveClient(client *Client) (string, error) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
-	if _, exists := g.clients[client.ClientId]; exists && client != nil {
-		delete(g.clients, client.ClientId)
-		return nil
+	if _, exists := g.clients[client.ClientId]; !exists || client == nil {
+		return "", fmt.Errorf("%w: you are not in this group", ty.ErrNoPermission)
+	}
+
+	account := strings.ToLower(client.GetAccount())
+	role := g.roleRequireLock(client.ClientId)
+	keepRole := g.Persistent && account != "" && role != ty.MemberRole
+
+	if keepRole {
+		g.accountRoles[account] = role
+	}
+
+	delete(g.clients, client.ClientId)
+	delete(g.moderators, client.ClientId)
+	delete(g.joined, client.ClientId)
+
+	if g.ownerId != client.ClientId {
+		return "", nil
 	}
 
-	return fmt.Errorf("%w: you are not in this group", ty.ErrNoPermission)
+	if keepRole {
+		g.ownerId = ""
+		return "", nil
+	}
+
+	g.ownerId = g.successorRequireLock()
+	delete(g.moderators, g.ownerId)
+
+	return g.ownerId, nil
+}
+
+// successorRequireLock returns the longest moderator or else the longest member, empty if the group is empty
+func (g *Group) successorRequireLock() string {
+	var successor string
+
+	for clientId, joined := range g.joined {
+		if successor == "" || g.moderators[clientId] && !g.moderators[successor] ||
+			g.moderators[clientId] == g.moderators[successor] && joined.Before(g.joined[successor]) {
+			successor = clientId
+		}
+	}
+
+	return successor
 }
 
 // GetClientIdsFromGroup return every clientId which is not in a rtc with given clientId
@@ -106,6 +293,122 @@ func (g *Group) GetClientIdsFromGroup(ownId string, onlyCallable bool) []string
 	return clientIds
 }
 
+// IsOwner reports whether the client owns the group
+func (g *Group) IsOwner(clientId string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.ownerId == clientId
+}
+
+// Role returns the role of the member, empty if the client isn't a member
+func (g *Group) Role(clientId string) string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.roleRequireLock(clientId)
+}
+
+func (g *Group) roleRequireLock(clientId string) string {
+	switch {
+	case g.ownerId == clientId:
+		return ty.OwnerRole
+	case g.moderators[clientId]:
+		return ty.ModeratorRole
+	}
+
+	if _, member := g.clients[clientId]; member {
+		return ty.MemberRole
+	}
+
+	return ""
+}
+
+// SetModerator promotes or demotes the member
+func (g *Group) SetModerator(clientId string, moderator bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.roleRequireLock(clientId) {
+	case "":
+		return fmt.Errorf("%w: the client is not in this group", ty.ErrNotAvailable)
+	case ty.OwnerRole:
+		return fmt.Errorf("%w: the role of the owner can't be changed", ty.ErrNoPermission)
+	}
+
+	if moderator {
+		g.moderators[clientId] = true
+	} else {
+		delete(g.moderators, clientId)
+	}
+
+	return nil
+}
+
+// TransferOwnership makes the member owner of the group, the previous owner becomes moderator
+func (g *Group) TransferOwnership(clientId string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	switch g.roleRequireLock(clientId) {
+	case "":
+		return fmt.Errorf("%w: the client is not in this group", ty.ErrNotAvailable)
+	case ty.OwnerRole:
+		return fmt.Errorf("%w: you already own this group", ty.ErrNoPermission)
+	}
+
+	delete(g.moderators, clientId)
+	g.moderators[g.ownerId] = true
+	g.ownerId = clientId
+
+	return nil
+}
+
+// SetBanned bans or unbans the client and its account from joining the group, the account
+// may be empty for guests. A role kept for the account is dropped with the ban
+func (g *Group) SetBanned(clientId string, account string, banned bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	account = strings.ToLower(account)
+	isBanned := g.banned[clientId] || account != "" && g.accountBans[account]
+
+	switch {
+	case banned && isBanned:
+		return fmt.Errorf("%w: the client is already banned", ty.ErrNoPermission)
+	case !banned && !isBanned:
+		return fmt.Errorf("%w: the client is not banned", ty.ErrNotAvailable)
+	}
+
+	if !banned {
+		delete(g.banned, clientId)
+		delete(g.accountBans, account)
+		return nil
+	}
+
+	g.banned[clientId] = true
+
+	if account != "" {
+		g.accountBans[account] = true
+		delete(g.accountRoles, account)
+	}
+
+	return nil
+}
+
+// IsBanned reports whether the client or its account is banned from the group
+func (g *Group) IsBanned(client *Client) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.isBannedRequireLock(client)
+}
+
+func (g *Group) isBannedRequireLock(client *Client) bool {
+	account := strings.ToLower(client.GetAccount())
+	return g.banned[client.ClientId] || account != "" && g.accountBans[account]
+}
+
 func (g *Group) GetClients() map[string]*Client {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
@@ -113,6 +416,134 @@ func (g *Group) GetClients() map[string]*Client {
 	return g.clients
 }
 
+// IsPersistent reports whether the group is kept while empty
+func (g *Group) IsPersistent() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.Persistent
+}
+
+// SetPersistent flags the group as persistent or as ad-hoc group, which forgets the roles of members who left
+func (g *Group) SetPersistent(persistent bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.Persistent = persistent
+
+	if !persistent {
+		clear(g.accountRoles)
+	}
+}
+
+// SetTopic sets the topic of the group, an empty topic removes it
+func (g *Group) SetTopic(topic string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.Topic = topic
+}
+
+func (g *Group) GetTopic() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return g.Topic
+}
+
+// Rename sets the name of the group
+func (g *Group) Rename(name string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.Name = name
+}
+
+// infoRow returns the group as row of the /group info table, the owner and moderators by name
+func (g *Group) infoRow() groupInfoRow {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	row := groupInfoRow{Name: g.Name, GroupId: g.GroupId, Topic: g.Topic, Visibility: g.Visibility, Size: len(g.clients),
+		Persistent: g.Persistent}
+
+	moderators := []string{}
+	for clientId, client := range g.clients {
+		switch g.roleRequireLock(clientId) {
+		case ty.OwnerRole:
+			row.Owner = client.GetName()
+		case ty.ModeratorRole:
+			moderators = append(moderators, client.GetName())
+		}
+	}
+
+	slices.Sort(moderators)
+	row.Moderators = strings.Join(moderators, ", ")
+
+	return row
+}
+
+// toJson returns the group as it is sent to clients
+func (g *Group) toJson() *ty.JsonGroup {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	return &ty.JsonGroup{GroupId: g.GroupId, Name: g.Name, Size: len(g.clients), Visibility: g.Visibility, Protected: g.Protected,
+		Persistent: g.Persistent, Topic: g.Topic}
+}
+
+// toRecord returns the group as it is saved in the group store, with the roles of
+// the members who left and those of the current members with an account
+func (g *Group) toRecord() *GroupRecord {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	roles := maps.Clone(g.accountRoles)
+	for clientId, client := range g.clients {
+		account := strings.ToLower(client.GetAccount())
+		if role := g.roleRequireLock(clientId); account != "" && role != ty.MemberRole {
+			roles[account] = role
+		}
+	}
+
+	record := &GroupRecord{GroupId: g.GroupId, Name: g.Name, Visibility: g.Visibility, PasswordHash: g.passwordHash, Topic: g.Topic}
+
+	for account, role := range roles {
+		if role == ty.OwnerRole {
+			record.Owner = account
+		} else {
+			record.Moderators = append(record.Moderators, account)
+		}
+	}
+
+	slices.Sort(record.Moderators)
+
+	record.Banned = slices.Sorted(maps.Keys(g.accountBans))
+
+	return record
+}
+
+// CheckPassword returns an error if the group is protected and the password doesn't fit
+func (g *Group) CheckPassword(password string) error {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if !g.Protected {
+		return nil
+	}
+
+	if password == "" {
+		return fmt.Errorf("%w: this group is protected, join it with '/group join {name|groupId} {password}'", ty.ErrNoPermission)
+	}
+
+	err := bcrypt.CompareHashAndPassword(g.passwordHash, []byte(password))
+	if err != nil {
+		return fmt.Errorf("%w: wrong password", ty.ErrNoPermission)
+	}
+
+	return nil
+}
+
 func (g *Group) SetSize() int {
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -0,0 +1,78 @@
+package chat
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestOutranks(t *testing.T) {
+	roles := []string{ty.OwnerRole, ty.ModeratorRole, ty.MemberRole, ""}
+
+	allowed := map[string][]string{
+		ty.OwnerRole:     {ty.ModeratorRole, ty.MemberRole, ""},
+		ty.ModeratorRole: {ty.MemberRole, ""},
+	}
+
+	for _, role := range roles {
+		for _, other := range roles {
+			assert.Equal(t, slices.Contains(allowed[role], other), outranks(role, other), "%q -> %q", role, other)
+		}
+	}
+}
+
+func TestGroupRoles(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+	register(t, pr, ClientId3, ClientName3)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Room").Error)
+	group := groupNamed(t, service, "Room")
+
+	assert.Equal(t, ty.OwnerRole, group.Role(ClientId))
+	assert.Equal(t, ty.MemberRole, group.Role(ClientId2))
+
+	// only the owner changes roles
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "promote "+ClientName3).Error, ty.ErrNoPermission)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "promote "+ClientName2).Error)
+	assert.Equal(t, ty.ModeratorRole, group.Role(ClientId2))
+
+	// moderators only moderate members
+	assert.ErrorIs(t, execute(t, pr, ClientId3, "/group", "kick "+ClientName2).Error, ty.ErrNoPermission)
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "kick "+ClientName).Error, ty.ErrNoPermission)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "kick "+ClientName3).Error)
+	assert.False(t, isMember(group, ClientId3))
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "demote "+ClientName2).Error)
+	assert.Equal(t, ty.MemberRole, group.Role(ClientId2))
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "transfer "+ClientName2).Error)
+	assert.Equal(t, ty.OwnerRole, group.Role(ClientId2))
+	assert.Equal(t, ty.ModeratorRole, group.Role(ClientId))
+}
+
+func TestGroupOwnerSuccession(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+	register(t, pr, ClientId3, ClientName3)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Room").Error)
+	group := groupNamed(t, service, "Room")
+
+	// moderators succeed before longer members
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "promote "+ClientName3).Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "leave").Error)
+	assert.Equal(t, ty.OwnerRole, group.Role(ClientId3))
+
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "leave").Error)
+	assert.Equal(t, ty.OwnerRole, group.Role(ClientId2))
+}
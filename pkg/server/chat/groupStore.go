@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// GroupRecord is a persistent group as it is saved in the group store. The roles are bound
+// to the lowercased account names, since clientIds don't survive a restart
+type GroupRecord struct {
+	GroupId      string   `json:"groupId"`
+	Name         string   `json:"name"`
+	Visibility   string   `json:"visibility"`
+	PasswordHash []byte   `json:"passwordHash,omitempty"`
+	Owner        string   `json:"owner,omitempty"`
+	Moderators   []string `json:"moderators,omitempty"`
+	Topic        string   `json:"topic,omitempty"`
+	// accounts banned from the group
+	Banned []string `json:"banned,omitempty"`
+}
+
+// GroupStore holds the persistent groups and saves them as json file if a path is set
+type GroupStore struct {
+	records []*GroupRecord
+	path    string
+	// content of the last written file, it isn't written again if nothing changed
+	saved []byte
+	mu    sync.Mutex
+}
+
+// NewGroupStore loads the groups from the file at path, if path is empty
+// the groups are only kept in memory
+func NewGroupStore(path string) (*GroupStore, error) {
+	gs := &GroupStore{records: []*GroupRecord{}, path: path}
+	if path == "" {
+		return gs, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return gs, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: group file couldn't be read", err)
+	}
+
+	err = json.Unmarshal(data, &gs.records)
+	if err != nil {
+		return nil, fmt.Errorf("%w: group file couldn't be parsed", err)
+	}
+
+	gs.saved = data
+
+	return gs, nil
+}
+
+// Records returns the stored groups
+func (gs *GroupStore) Records() []*GroupRecord {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	return slices.Clone(gs.records)
+}
+
+// Save replaces the stored groups with the records and writes them into a temporary
+// file, which replaces the group file
+func (gs *GroupStore) Save(records []*GroupRecord) error {
+	slices.SortFunc(records, func(a, b *GroupRecord) int {
+		return strings.Compare(a.GroupId, b.GroupId)
+	})
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	gs.records = records
+
+	if gs.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: error parsing groups to json", err)
+	}
+
+	if bytes.Equal(data, gs.saved) {
+		return nil
+	}
+
+	tmpPath := gs.path + ".tmp"
+
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("%w: group file couldn't be written", err)
+	}
+
+	err = os.Rename(tmpPath, gs.path)
+	if err != nil {
+		return fmt.Errorf("%w: group file couldn't be replaced", err)
+	}
+
+	gs.saved = data
+
+	return nil
+}
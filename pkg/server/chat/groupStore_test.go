@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestGroupStore(t *testing.T) {
+	path := t.TempDir() + "/groups.json"
+
+	gs, err := NewGroupStore(path)
+	assert.Nil(t, err)
+	assert.Empty(t, gs.Records())
+
+	room := &GroupRecord{GroupId: "b", Name: "Room", Visibility: ty.PrivateGroup, Owner: "arndt", Moderators: []string{"len"}, Topic: "topic"}
+	hall := &GroupRecord{GroupId: "a", Name: "Hall", Visibility: ty.PublicGroup, Banned: []string{"kim"}}
+	assert.Nil(t, gs.Save([]*GroupRecord{room, hall}))
+
+	gs, err = NewGroupStore(path)
+	assert.Nil(t, err)
+
+	loaded := gs.Records()
+	assert.Len(t, loaded, 2)
+	assert.Equal(t, hall, loaded[0])
+	assert.Equal(t, room, loaded[1])
+
+	// the temporary file replaced the group file
+	_, err = os.Stat(path + ".tmp")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestGroupStoreWithoutFile(t *testing.T) {
+	gs, err := NewGroupStore("")
+	assert.Nil(t, err)
+
+	assert.Nil(t, gs.Save([]*GroupRecord{{GroupId: "a", Name: "Hall"}}))
+	assert.Len(t, gs.Records(), 1)
+
+	path := t.TempDir() + "/groups.json"
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	_, err = NewGroupStore(path)
+	assert.NotNil(t, err)
+}
+
+func TestPersistentGroupsAreRestored(t *testing.T) {
+	path := t.TempDir() + "/groups.json"
+
+	store, err := NewGroupStore(path)
+	assert.Nil(t, err)
+
+	service, pr := newTestService(t, nil)
+	service.RestoreGroups(store)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/signup", ClientName+" "+Password).Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/signup", ClientName2+" "+Password).Error)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room --private --password "+Password).Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "persist on").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "topic plans").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "invite "+ClientName2).Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "accept").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "promote "+ClientName2).Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Adhoc").Error)
+
+	// the roles of the members are saved on shutdown
+	service.LogOutAllUsers()
+
+	// a restarted server only knows the persistent group
+	store, err = NewGroupStore(path)
+	assert.Nil(t, err)
+	assert.Len(t, store.Records(), 1)
+
+	service, pr = newTestService(t, nil)
+	service.RestoreGroups(store)
+
+	group := groupNamed(t, service, "Room")
+	assert.True(t, group.IsPersistent())
+	assert.Equal(t, ty.PrivateGroup, group.Visibility)
+	assert.Equal(t, "plans", group.GetTopic())
+
+	// the accounts get their roles back without password
+	assert.Nil(t, execute(t, pr, ClientId3, "/signup", ClientName2+" "+Password).Error)
+	assert.Nil(t, execute(t, pr, ClientId4, "/signup", ClientName+" "+Password).Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId4, "/group", "join Room").Error)
+
+	assert.Equal(t, ty.ModeratorRole, group.Role(ClientId3))
+	assert.Equal(t, ty.OwnerRole, group.Role(ClientId4))
+}
+
+func TestPersistentGroupsNeedAnOwnerAccount(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	register(t, pr, ClientId, ClientName)
+	assert.Nil(t, execute(t, pr, ClientId2, "/signup", ClientName2+" "+Password).Error)
+	register(t, pr, ClientId3, ClientName3)
+
+	// a guest can't keep the group, nobody could prove to be its owner after a restart
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "persist on").Error, ty.ErrNoPermission)
+
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "create Hall").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "persist on").Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Hall").Error)
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "transfer "+ClientName3).Error, ty.ErrNoPermission)
+
+	// a persistent group without owner account isn't taken over by the next member
+	group := groupNamed(t, service, "Room")
+	assert.Nil(t, service.SetGroupPersistent(group.GroupId, true))
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "leave").Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Room").Error)
+
+	assert.Equal(t, ty.MemberRole, group.Role(ClientId3))
+}
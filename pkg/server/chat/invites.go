@@ -0,0 +1,138 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// Invite is a pending invitation of a client into a group
+type Invite struct {
+	group     *Group
+	inviterId string
+	inviter   string
+	expires   time.Time
+}
+
+// toJson returns the invite as it is sent to clients
+func (i *Invite) toJson() *ty.JsonInvite {
+	return &ty.JsonInvite{Group: i.group.toJson(), InviterId: i.inviterId, Inviter: i.inviter, Expires: i.expires}
+}
+
+// inviteRow is an invitation as row of the /group invites table
+type inviteRow struct {
+	Group     string `json:"group"`
+	GroupId   string `json:"groupId"`
+	Inviter   string `json:"inviter"`
+	ExpiresIn string `json:"expiresIn"`
+}
+
+func (i *Invite) toRow() inviteRow {
+	return inviteRow{
+		Group:     i.group.Name,
+		GroupId:   i.group.GroupId,
+		Inviter:   i.inviter,
+		ExpiresIn: time.Until(i.expires).Round(time.Second).String(),
+	}
+}
+
+// Invite stores an invitation of the invitee into the group, which expires after the configured
+// inviteTimeout. Inviting a client again refreshes its invitation
+func (s *ChatService) Invite(group *Group, inviter Caller, inviteeId string) (*Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.clients[inviteeId]; !exists {
+		return nil, fmt.Errorf("%w: there is no client with id: %s registered", ty.ErrNotAvailable, inviteeId)
+	}
+
+	if inviteeId == inviter.ClientId {
+		return nil, fmt.Errorf("%w: you can't invite yourself", ty.ErrNoPermission)
+	}
+
+	if _, member := group.GetClients()[inviteeId]; member {
+		return nil, fmt.Errorf("%w: the client is already in this group", ty.ErrNoPermission)
+	}
+
+	invite := &Invite{
+		group:     group,
+		inviterId: inviter.ClientId,
+		inviter:   inviter.Name,
+		expires:   time.Now().UTC().Add(time.Duration(s.config.Get().InviteTimeout)),
+	}
+
+	if s.invites[inviteeId] == nil {
+		s.invites[inviteeId] = make(map[string]*Invite)
+	}
+
+	s.invites[inviteeId][group.GroupId] = invite
+
+	return invite, nil
+}
+
+// TakeInvite removes the pending invitation of the client into the group and returns it.
+// If groupId is empty the newest invitation is taken
+func (s *ChatService) TakeInvite(clientId string, groupId string) (*Invite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneInvitesRequireLock(time.Now().UTC())
+
+	if groupId == "" {
+		invites := s.invitesRequireLock(clientId)
+		if len(invites) < 1 {
+			return nil, fmt.Errorf("%w: you have no pending invitations", ty.ErrNotAvailable)
+		}
+
+		groupId = invites[len(invites)-1].group.GroupId
+	}
+
+	invite, exists := s.invites[clientId][groupId]
+	if !exists {
+		return nil, fmt.Errorf("%w: you have no pending invitation into the group %s", ty.ErrNotAvailable, groupId)
+	}
+
+	delete(s.invites[clientId], groupId)
+
+	return invite, nil
+}
+
+// Invites returns the pending invitations of the client, the newest one last
+func (s *ChatService) Invites(clientId string) []*Invite {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pruneInvitesRequireLock(time.Now().UTC())
+
+	return s.invitesRequireLock(clientId)
+}
+
+func (s *ChatService) invitesRequireLock(clientId string) []*Invite {
+	invites := []*Invite{}
+	for _, invite := range s.invites[clientId] {
+		invites = append(invites, invite)
+	}
+
+	slices.SortFunc(invites, func(a, b *Invite) int {
+		return a.expires.Compare(b.expires)
+	})
+
+	return invites
+}
+
+// pruneInvitesRequireLock deletes expired invitations and those into deleted groups
+func (s *ChatService) pruneInvitesRequireLock(now time.Time) {
+	for clientId, invites := range s.invites {
+		for groupId, invite := range invites {
+			if _, exists := s.groups[groupId]; !exists || now.After(invite.expires) {
+				delete(invites, groupId)
+			}
+		}
+
+		if len(invites) < 1 {
+			delete(s.invites, clientId)
+		}
+	}
+}
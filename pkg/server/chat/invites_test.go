@@ -0,0 +1,101 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestInviteAccept(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	group := groupNamed(t, service, "Room")
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "invite "+ClientName2).Error)
+
+	invites := service.Invites(ClientId2)
+	assert.Len(t, invites, 1)
+	assert.Equal(t, group, invites[0].group)
+	assert.Equal(t, ClientId, invites[0].inviterId)
+
+	rsp := execute(t, pr, ClientId2, "/group", "accept")
+	assert.Nil(t, rsp.Error)
+	assert.Equal(t, ty.KindGroupJoined, rsp.Kind)
+	assert.True(t, isMember(group, ClientId2))
+
+	// the invitation is used up
+	assert.Empty(t, service.Invites(ClientId2))
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "accept").Error, ty.ErrNotAvailable)
+}
+
+func TestInviteDecline(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	group := groupNamed(t, service, "Room")
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "invite "+ClientName2).Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "decline "+group.GroupId).Error)
+
+	assert.False(t, isMember(group, ClientId2))
+	assert.Empty(t, service.Invites(ClientId2))
+}
+
+func TestInviteErrors(t *testing.T) {
+	_, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+	register(t, pr, ClientId3, ClientName3)
+
+	// only members can invite into their group
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "invite "+ClientName2).Error, ty.ErrNoPermission)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "invite "+ClientName).Error, ty.ErrNoPermission)
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "invite "+ClientName2).Error, ty.ErrNoPermission)
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/group", "invite Nobody").Error, ty.ErrNotAvailable)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "invite "+ClientName3).Error)
+}
+
+func TestInviteExpires(t *testing.T) {
+	service, pr := newTestService(t, func(cfg *config.Config) { cfg.InviteTimeout = config.Duration(50 * time.Millisecond) })
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "invite "+ClientName2).Error)
+	assert.Len(t, service.Invites(ClientId2), 1)
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Empty(t, service.Invites(ClientId2))
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "accept").Error, ty.ErrNotAvailable)
+}
+
+func TestInviteIntoPrivateGroup(t *testing.T) {
+	service, pr := newTestService(t, nil)
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room --private").Error)
+	group := groupNamed(t, service, "Room")
+
+	// private groups aren't revealed without invitation
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/group", "join "+group.GroupId).Error, ty.ErrNotAvailable)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "invite "+ClientName2).Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join "+group.GroupId).Error)
+	assert.True(t, isMember(group, ClientId2))
+}
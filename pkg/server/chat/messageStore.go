@@ -0,0 +1,142 @@
+package chat
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// MessageStore records chat messages so they can be replayed later
+type MessageStore interface {
+	Append(msg ty.JsonMessage) error
+	// Last returns up to n of the newest messages matching the filter in chronological order
+	Last(n int, filter func(ty.JsonMessage) bool) ([]ty.JsonMessage, error)
+	Close() error
+}
+
+// MemoryStore keeps the newest messages in a ring buffer with a fixed capacity
+type MemoryStore struct {
+	messages []ty.JsonMessage
+	start    int
+	count    int
+	mu       sync.RWMutex
+}
+
+func NewMemoryStore(capacity int) *MemoryStore {
+	return &MemoryStore{messages: make([]ty.JsonMessage, capacity)}
+}
+
+// Append adds a message and overwrites the oldest one if the store is full
+func (ms *MemoryStore) Append(msg ty.JsonMessage) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if len(ms.messages) == 0 {
+		return nil
+	}
+
+	ms.messages[(ms.start+ms.count)%len(ms.messages)] = msg
+
+	if ms.count < len(ms.messages) {
+		ms.count++
+		return nil
+	}
+
+	ms.start = (ms.start + 1) % len(ms.messages)
+	return nil
+}
+
+func (ms *MemoryStore) Last(n int, filter func(ty.JsonMessage) bool) ([]ty.JsonMessage, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var result []ty.JsonMessage
+
+	for i := ms.count - 1; i >= 0 && len(result) < n; i-- {
+		msg := ms.messages[(ms.start+i)%len(ms.messages)]
+		if filter == nil || filter(msg) {
+			result = append(result, msg)
+		}
+	}
+
+	slices.Reverse(result)
+	return result, nil
+}
+
+func (ms *MemoryStore) Close() error {
+	return nil
+}
+
+// FileStore appends every message as a json line to a file and keeps the newest
+// messages in memory to answer queries
+type FileStore struct {
+	memory *MemoryStore
+	file   *os.File
+	mu     sync.Mutex
+}
+
+// NewFileStore opens or creates the file at path and loads the newest messages of it
+func NewFileStore(path string, capacity int) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("%w: message history file couldn't be opened", err)
+	}
+
+	fs := &FileStore{memory: NewMemoryStore(capacity), file: file}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 2<<20)
+
+	for scanner.Scan() {
+		var msg ty.JsonMessage
+
+		err = json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			fmt.Printf("\n%v: skipping corrupt line in message history", err)
+			continue
+		}
+
+		fs.memory.Append(msg)
+	}
+
+	if err = scanner.Err(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: message history file couldn't be read", err)
+	}
+
+	return fs, nil
+}
+
+// Append writes the message to the file before adding it to the in-memory buffer
+func (fs *FileStore) Append(msg ty.JsonMessage) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("%w: error parsing message to json", err)
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	_, err = fs.file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("%w: message couldn't be written to history file", err)
+	}
+
+	return fs.memory.Append(msg)
+}
+
+func (fs *FileStore) Last(n int, filter func(ty.JsonMessage) bool) ([]ty.JsonMessage, error) {
+	return fs.memory.Last(n, filter)
+}
+
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.file.Close()
+}
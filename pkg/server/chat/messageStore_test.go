@@ -0,0 +1,169 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// contents returns the contents of the messages in their order
+func contents(messages []ty.JsonMessage) []string {
+	result := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, msg.Content)
+	}
+
+	return result
+}
+
+// historyContents returns the contents of the messages of a history response
+func historyContents(t *testing.T, rsp *ty.Response) []string {
+	assert.Nil(t, rsp.Error)
+	assert.Equal(t, ty.KindHistory, rsp.Kind)
+
+	var payload ty.HistoryPayload
+	assert.Nil(t, rsp.DecodePayload(&payload))
+
+	result := make([]string, 0, len(payload.Messages))
+	for _, msg := range payload.Messages {
+		result = append(result, msg.Content)
+	}
+
+	return result
+}
+
+// sendPrivate sends a private message, which addresses the receiver with Message.ClientId
+func sendPrivate(t *testing.T, pr *PluginRegistry, clientId string, receiverId string, content string) {
+	client, err := pr.s.GetClient(clientId)
+	assert.Nil(t, err)
+
+	rsp, err := pr.FindAndExecute(WithCaller(context.Background(), client.Caller()),
+		&ty.Message{Name: client.Caller().Name, Plugin: "/private", Content: content, ClientId: receiverId})
+	assert.Nil(t, err)
+	assert.Nil(t, rsp.Error)
+}
+
+func TestMemoryStoreWrapsAround(t *testing.T) {
+	store := NewMemoryStore(3)
+
+	for _, content := range []string{"1", "2", "3", "4", "5"} {
+		assert.Nil(t, store.Append(ty.JsonMessage{Scope: ty.LobbyScope, Content: content}))
+	}
+
+	// the oldest messages are overwritten
+	messages, err := store.Last(10, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "4", "5"}, contents(messages))
+
+	messages, err = store.Last(2, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"4", "5"}, contents(messages))
+
+	messages, err = store.Last(10, func(m ty.JsonMessage) bool { return m.Content != "4" })
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"3", "5"}, contents(messages))
+
+	// a store without capacity keeps nothing
+	empty := NewMemoryStore(0)
+	assert.Nil(t, empty.Append(ty.JsonMessage{Content: "1"}))
+
+	messages, err = empty.Last(10, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestFileStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+
+	store, err := NewFileStore(path, 10)
+	assert.Nil(t, err)
+
+	for _, content := range []string{"1", "2", "3"} {
+		assert.Nil(t, store.Append(ty.JsonMessage{Scope: ty.LobbyScope, Content: content}))
+	}
+	assert.Nil(t, store.Close())
+
+	// corrupt lines between valid ones are skipped
+	line, err := json.Marshal(ty.JsonMessage{Scope: ty.LobbyScope, Content: "4"})
+	assert.Nil(t, err)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	assert.Nil(t, err)
+	_, err = file.WriteString("{\"scope\": \"lobby\", \"cont\n" + string(line) + "\n")
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+
+	store, err = NewFileStore(path, 10)
+	assert.Nil(t, err)
+
+	messages, err := store.Last(10, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1", "2", "3", "4"}, contents(messages))
+
+	assert.Nil(t, store.Append(ty.JsonMessage{Scope: ty.LobbyScope, Content: "5"}))
+	assert.Nil(t, store.Close())
+
+	// only the newest messages are loaded into a smaller buffer
+	store, err = NewFileStore(path, 2)
+	assert.Nil(t, err)
+	defer store.Close()
+
+	messages, err = store.Last(10, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"4", "5"}, contents(messages))
+}
+
+func TestHistoryFilters(t *testing.T) {
+	_, pr := newTestService(t, nil)
+
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+	register(t, pr, ClientId3, ClientName3)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/broadcast", "lobby").Error)
+	sendPrivate(t, pr, ClientId, ClientId2, "secret")
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/broadcast", "group").Error)
+
+	// private messages are only replayed to their sender and receiver
+	assert.Equal(t, []string{"lobby", "secret"}, historyContents(t, execute(t, pr, ClientId, "/history", "")))
+	assert.Equal(t, []string{"lobby", "secret"}, historyContents(t, execute(t, pr, ClientId2, "/history", "")))
+	assert.Equal(t, []string{"lobby"}, historyContents(t, execute(t, pr, ClientId3, "/history", "")))
+	assert.Equal(t, []string{"secret"}, historyContents(t, execute(t, pr, ClientId2, "/history", "1")))
+
+	// the group history only contains messages of the group
+	assert.Equal(t, []string{"group"}, historyContents(t, execute(t, pr, ClientId3, "/group", "history")))
+	assert.NotNil(t, execute(t, pr, ClientId, "/group", "history").Error)
+
+	assert.ErrorIs(t, execute(t, pr, ClientId, "/history", "0").Error, ty.ErrParsing)
+}
+
+func TestPrivateHistoryFollowsAccount(t *testing.T) {
+	_, pr := newTestService(t, nil)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/signup", ClientName+" "+Password).Error)
+	register(t, pr, ClientId2, ClientName2)
+	register(t, pr, ClientId3, ClientName3)
+
+	sendPrivate(t, pr, ClientId, ClientId2, "to the guest")
+	sendPrivate(t, pr, ClientId2, ClientId, "to the account")
+	sendPrivate(t, pr, ClientId3, ClientId2, "between guests")
+
+	// a new session of the account gets its private messages back
+	execute(t, pr, ClientId, "/quit", "")
+	assert.Nil(t, execute(t, pr, ClientId4, "/login", ClientName+" "+Password).Error)
+	assert.Equal(t, []string{"to the guest", "to the account"}, historyContents(t, execute(t, pr, ClientId4, "/history", "")))
+
+	// guests are only identified by their clientId, not by their name
+	clientId := "clientId5-NnLrLWnbuhf-LgBUAdAxdZf-U1pgRw"
+
+	execute(t, pr, ClientId2, "/quit", "")
+	register(t, pr, clientId, ClientName2)
+	assert.Empty(t, historyContents(t, execute(t, pr, clientId, "/history", "")))
+}
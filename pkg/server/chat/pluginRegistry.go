@@ -1,18 +1,23 @@
 package chat
 
 import (
+	"context"
 	"fmt"
 
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
 
 type PluginInterface interface {
-	Execute(message *ty.Message) (*ty.Response, error)
+	Execute(ctx context.Context, message *ty.Message) (*ty.Response, error)
 	Description() *Description
+	// CheckScope returns the scope the caller has to fulfill to execute the plugin
+	CheckScope() int
 }
 
 type PluginRegistry struct {
 	plugins map[string]PluginInterface
+	s       *ChatService
 }
 
 type Plugin struct {
@@ -26,26 +31,53 @@ type Description struct {
 }
 
 // RegisterPlugins sets up all the plugins
-func RegisterPlugins(chatService *ChatService) *PluginRegistry {
-	pr := &PluginRegistry{plugins: make(map[string]PluginInterface)}
+func RegisterPlugins(chatService *ChatService, accounts *AccountStore, cfg *config.Holder) *PluginRegistry {
+	pr := &PluginRegistry{plugins: make(map[string]PluginInterface), s: chatService}
 	pr.plugins["/help"] = NewHelpPlugin(pr)
 	pr.plugins["/time"] = NewTimePlugin()
 	pr.plugins["/users"] = NewListUsersPlugin(chatService)
-	pr.plugins["/register"] = NewRegisterClientPlugin(chatService, pr)
+	pr.plugins["/register"] = NewRegisterClientPlugin(chatService, pr, accounts)
+	pr.plugins["/signup"] = NewSignupPlugin(chatService, accounts)
+	pr.plugins["/login"] = NewLoginPlugin(chatService, accounts)
+	pr.plugins["/nick"] = NewNickPlugin(chatService, accounts)
 	pr.plugins["/broadcast"] = NewBroadcastPlugin(chatService)
 	pr.plugins["/quit"] = NewLogOutPlugin(chatService, pr)
 	pr.plugins["/private"] = NewPrivateMessagePlugin(chatService)
-	pr.plugins["/group"] = RegisterGroupPlugins(chatService, pr)
-	pr.plugins["/call"] = NewCallPlugin(chatService)
+	pr.plugins["/group"] = RegisterGroupPlugins(chatService)
+	pr.plugins["/call"] = NewCallPlugin(chatService, cfg)
+	pr.plugins["/history"] = NewHistoryPlugin(chatService)
 
 	return pr
 }
 
-func (pr *PluginRegistry) FindAndExecute(message *ty.Message) (*ty.Response, error) {
+// FindAndExecute executes the plugin of the message as the caller of the context, messages
+// claiming another sender are rejected with an IdentityError and callers not fulfilling the
+// scope of the plugin with a ScopeError
+func (pr *PluginRegistry) FindAndExecute(ctx context.Context, message *ty.Message) (*ty.Response, error) {
 	plugin, ok := pr.plugins[message.Plugin]
 	if !ok {
-		return &ty.Response{Err: fmt.Sprintf("%v: no such chat plugin found: %s", ty.ErrNoPermission, message.Plugin)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: no such chat plugin found: %s", ty.ErrNoPermission, message.Plugin)), nil
 	}
 
-	return plugin.Execute(message)
+	err := checkScope(ctx, pr.s, message.Plugin, plugin.CheckScope(), message.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	caller := CallerFrom(ctx)
+
+	err = checkClaim("name", message.Name, caller.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// private messages address the receiver with Message.ClientId
+	if message.Plugin != "/private" {
+		err = checkClaim("clientId", message.ClientId, caller.ClientId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plugin.Execute(ctx, message)
 }
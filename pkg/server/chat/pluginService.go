@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
@@ -27,23 +28,197 @@ func ListPlugins(plugins map[string]PluginInterface) []json.RawMessage {
 	return jsonSlice
 }
 
-func GetCurrentGroup(clientId string, s *ChatService) (*Group, *Client, error) {
+// GetTargetGroup returns the group of the client addressed by groupId or its active group if
+// groupId is empty, the group is nil if the lobby is active
+func GetTargetGroup(clientId string, groupId string, s *ChatService) (*Group, *Client, error) {
 	client, err := s.GetClient(clientId)
 	if err != nil {
 		return nil, nil, fmt.Errorf("%w: client (probably) already deleted", err)
 	}
 
-	groupId := client.GetGroupId()
-	if groupId == "" {
-		return nil, client, nil
+	group, err := client.GetGroup(groupId)
+	if err != nil {
+		return nil, client, err
+	}
+
+	return group, client, nil
+}
+
+// broadcastNotice broadcasts an event like joining or leaving a group into the group
+// or the lobby if group is nil, notices aren't recorded
+func broadcastNotice(s *ChatService, group *Group, rsp *ty.Response) {
+	if group != nil {
+		rsp.GroupId = group.GroupId
+		s.Broadcast(group.GetClients(), rsp)
+		return
 	}
 
-	group, err := s.GetGroup(groupId)
+	s.Broadcast(nil, rsp)
+}
+
+// groupNotice broadcasts the notice into the group and returns it as response for the caller,
+// who is skipped by the broadcast
+func groupNotice(s *ChatService, group *Group, callerId string, text string) *ty.Response {
+	rsp := ty.NoticeEvent(text)
+	rsp.ClientId = callerId
+	rsp.GroupId = group.GroupId
+	s.Broadcast(group.GetClients(), rsp)
+
+	return rsp
+}
+
+// groupUpdate broadcasts the changed group into it and returns the update as response for the
+// caller, who is skipped by the broadcast
+func groupUpdate(s *ChatService, group *Group, callerId string, reason string) *ty.Response {
+	rsp := ty.GroupUpdatedEvent(group.toJson(), reason)
+	rsp.ClientId = callerId
+	rsp.GroupId = group.GroupId
+	s.Broadcast(group.GetClients(), rsp)
+
+	return rsp
+}
+
+// leaveGroup removes the client from the group, ends its calls in the group and announces
+// it as well as the new owner, if the client owned the group, to the remaining members
+func leaveGroup(s *ChatService, group *Group, client *Client) error {
+	newOwnerId, err := group.RemoveClient(client)
 	if err != nil {
-		return nil, client, fmt.Errorf("%w: group not found", err)
+		return err
 	}
 
-	return group, client, nil
+	group.RemoveConnection(client.ClientId, "", true)
+	client.RemoveGroup(group)
+
+	broadcastNotice(s, group, ty.UserLeftEvent(client.ClientId, client.GetName()))
+	announceOwner(s, group, newOwnerId)
+
+	return nil
+}
+
+// announceOwner tells the members of the group who owns it now, nothing happens if ownerId is empty
+func announceOwner(s *ChatService, group *Group, ownerId string) {
+	if ownerId == "" {
+		return
+	}
+
+	owner, err := s.GetClient(ownerId)
+	if err != nil {
+		return
+	}
+
+	fmt.Printf("\nownership of group %s passed to %s", group.GroupId, ownerId)
+
+	broadcastNotice(s, group, ty.NoticeEvent(fmt.Sprintf("%s ist nun Besitzer der Gruppe %s", owner.GetName(), group.Name)))
+}
+
+// groupMember returns the group of the caller addressed by groupId and the member addressed
+// by content with its name or id, the caller can't address itself
+func groupMember(s *ChatService, caller Caller, groupId string, content string) (*Group, *Client, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, nil, fmt.Errorf("%w: expected {name|clientId}", ty.ErrParsing)
+	}
+
+	group, _, err := GetTargetGroup(caller.ClientId, groupId, s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: error getting target group", err)
+	}
+
+	if group == nil {
+		return nil, nil, fmt.Errorf("%w: you are not in a group", ty.ErrNoPermission)
+	}
+
+	group.mu.RLock()
+	member, err := resolveClient(group.clients, content)
+	group.mu.RUnlock()
+
+	if err != nil {
+		return group, nil, fmt.Errorf("%w (in this group)", err)
+	}
+
+	if member.ClientId == caller.ClientId {
+		return nil, nil, fmt.Errorf("%w: you can't use this command on yourself", ty.ErrNoPermission)
+	}
+
+	return group, member, nil
+}
+
+// outranks reports whether a member with the role may moderate a member with the other role,
+// the owner moderates everyone and moderators only members
+func outranks(role string, other string) bool {
+	switch role {
+	case ty.OwnerRole:
+		return other != ty.OwnerRole
+	case ty.ModeratorRole:
+		return other == ty.MemberRole || other == ""
+	}
+
+	return false
+}
+
+// password length limits, bcrypt only uses the first 72 bytes
+const minPasswordLength = 8
+const maxPasswordLength = 72
+
+// parseName trims the name of /register and /nick and returns an error if it isn't valid
+func parseName(content string) (string, error) {
+	name := strings.TrimSpace(content)
+
+	err := ty.CheckName(name)
+	if err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// parseCredentials splits the content of the account plugins into name and password
+func parseCredentials(content string) (string, string, error) {
+	fields := strings.Fields(content)
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("%w: expected {name} {password}", ty.ErrParsing)
+	}
+
+	name, password := fields[0], fields[1]
+
+	err := ty.CheckName(name)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(password) > maxPasswordLength || len(password) < minPasswordLength {
+		return "", "", fmt.Errorf("%w: your password has to be between %d and %d chars long", ty.ErrParsing, minPasswordLength, maxPasswordLength)
+	}
+
+	return name, password, nil
+}
+
+// default and maximum amount of messages replayed by the history plugins
+const defaultHistoryCount = 20
+const maxHistoryCount = 200
+
+// parseHistoryCount parses the optional message count of the history plugins
+func parseHistoryCount(content string) (int, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return defaultHistoryCount, nil
+	}
+
+	n, err := strconv.Atoi(content)
+	if err != nil || n < 1 || n > maxHistoryCount {
+		return 0, fmt.Errorf("%w: n has to be a number between 1 and %d", ty.ErrParsing, maxHistoryCount)
+	}
+
+	return n, nil
+}
+
+// historyResponse formats recorded messages to a history response, which is empty if there are none
+func historyResponse(messages []ty.JsonMessage) (*ty.Response, error) {
+	history := make([]*ty.JsonMessage, 0, len(messages))
+	for i := range messages {
+		history = append(history, &messages[i])
+	}
+
+	return ty.HistoryEvent(history), nil
 }
 
 func extractIdentifierMessage(msg *ty.Message) (*ty.Message, error) {
@@ -53,34 +228,30 @@ func extractIdentifierMessage(msg *ty.Message) (*ty.Message, error) {
 
 	identifier := strings.Fields(msg.Content)[0]
 	content, _ := strings.CutPrefix(msg.Content, fmt.Sprintf("%s ", identifier))
+	if strings.TrimSpace(content) == identifier {
+		content = ""
+	}
+
 	msg.Plugin = identifier
 	msg.Content = content
 
 	return msg, nil
 }
 
-func ClientsToJsonSliceRequireLock(clientsToIterate map[string]*Client, ownId string) []json.RawMessage {
-	var result []json.RawMessage
+func ClientsToJsonSliceRequireLock(clientsToIterate map[string]*Client, ownId string) []*ty.JsonClient {
+	var result []*ty.JsonClient
 
 	for _, item := range clientsToIterate {
 		if item.ClientId == ownId {
 			continue
 		}
-		client := ty.JsonClient{
-			Name:      item.Name,
+		result = append(result, &ty.JsonClient{
+			Name:      item.GetName(),
 			ClientId:  item.ClientId,
 			GroupName: item.GroupName,
 			CallState: item.GetCallState(ownId),
 			GroupId:   item.GetGroupId(),
-		}
-
-		jsonBytes, err := json.Marshal(client)
-		if err != nil {
-			log.Printf("error parsing item %T to json", item)
-			continue
-		}
-
-		result = append(result, jsonBytes)
+		})
 	}
 
 	return result
@@ -1,22 +1,24 @@
 package chat
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
-	"sync"
 	"time"
 
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
 
 // CallPlugin returns a slice of all the other group member ids
 type CallPlugin struct {
 	chatService *ChatService
+	config      *config.Holder
 }
 
-func NewCallPlugin(s *ChatService) *CallPlugin {
-	return &CallPlugin{chatService: s}
+func NewCallPlugin(s *ChatService, cfg *config.Holder) *CallPlugin {
+	return &CallPlugin{chatService: s, config: cfg}
 }
 
 func (cp *CallPlugin) Description() *Description {
@@ -26,23 +28,29 @@ func (cp *CallPlugin) Description() *Description {
 	}
 }
 
-func (cp *CallPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	fmt.Printf("\n[CallPlugin] Client '%s' (%s) requested to start a call", msg.Name, msg.ClientId)
+func (cp *CallPlugin) CheckScope() int {
+	return InGroupOnly
+}
+
+func (cp *CallPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+	fmt.Printf("\n[CallPlugin] Client '%s' (%s) requested to start a call", caller.Name, caller.ClientId)
 
-	group, _, err := GetCurrentGroup(msg.ClientId, cp.chatService)
+	group, _, err := GetTargetGroup(caller.ClientId, msg.GroupId, cp.chatService)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: error getting current group", err)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
 	}
 
 	if group == nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: you are not in a group yet", ty.ErrNoPermission)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: you are not in a group yet", ty.ErrNoPermission)), nil
 	}
 
-	if group.SetSize() > 6 {
-		return &ty.Response{Err: fmt.Sprintf("%v: group is to big for a call (max 6 clients)", ty.ErrNoPermission)}, nil
+	maxCallSize := cp.config.Get().MaxCallSize
+	if group.SetSize() > maxCallSize {
+		return ty.ErrorEvent(fmt.Errorf("%w: group is to big for a call (max %d clients)", ty.ErrNoPermission, maxCallSize)), nil
 	}
 
-	groupClientIds := group.GetClientIdsFromGroup(msg.ClientId, true)
+	groupClientIds := group.GetClientIdsFromGroup(caller.ClientId, true)
 	fmt.Printf("\n[CallPlugin] Group members for call (excluding caller): %v", groupClientIds)
 
 	jsonSlice := json.RawMessage{}
@@ -51,10 +59,10 @@ func (cp *CallPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
 		return nil, fmt.Errorf("%w: error encoding clientId to json", err)
 	}
 
-	return &ty.Response{RspName: msg.Name, Content: string(jsonSlice), Err: ty.IgnoreResponseTag}, nil
+	return &ty.Response{RspName: caller.Name, Content: string(jsonSlice), Err: ty.IgnoreResponseTag, GroupId: group.GroupId}, nil
 }
 
-// PrivateMessage Plugin lets a client send a private message to another client identified by it's clientId
+// PrivateMessage Plugin lets a client send a private message to another client identified by it's name or clientId
 type PrivateMessagePlugin struct {
 	chatService *ChatService
 }
@@ -66,23 +74,36 @@ func NewPrivateMessagePlugin(s *ChatService) *PrivateMessagePlugin {
 func (pp *PrivateMessagePlugin) Description() *Description {
 	return &Description{
 		Description: "lets you send a private message",
-		Template:    "/private {Id} {message}",
+		Template:    "/private {name|clientId} {message} | @{name} {message}",
 	}
 }
 
-func (pp *PrivateMessagePlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	client, err := pp.chatService.GetClient(msg.ClientId)
+func (pp *PrivateMessagePlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (pp *PrivateMessagePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	client, err := pp.chatService.ResolveClient(msg.ClientId)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: client with id: %s not found", err, msg.ClientId)}, nil
+		return ty.ErrorEvent(err), nil
 	}
 
-	rsp := &ty.Response{RspName: fmt.Sprintf("[%s]", msg.Name), Content: msg.Content}
+	caller := CallerFrom(ctx)
+	rsp := ty.ChatEvent(caller.Name, caller.ClientId, msg.Content, true)
 
 	err = client.Send(rsp)
 	if err != nil {
 		return nil, err
 	}
 
+	record := ty.JsonMessage{Scope: ty.PrivateScope, Sender: caller.Name, SenderId: caller.ClientId, ReceiverId: client.ClientId,
+		ReceiverAccount: client.GetAccount(), Content: msg.Content}
+	if sender, err := pp.chatService.GetClient(caller.ClientId); err == nil {
+		record.SenderAccount = sender.GetAccount()
+	}
+
+	pp.chatService.Record(record)
+
 	return rsp, nil
 }
 
@@ -106,26 +127,27 @@ func (lp *LogOutPlugin) Description() *Description {
 	}
 }
 
-func (lp *LogOutPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (lp *LogOutPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (lp *LogOutPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
 	lp.chatService.mu.Lock()
 	defer lp.chatService.mu.Unlock()
 
-	client, ok := lp.chatService.clients[msg.ClientId]
+	caller := CallerFrom(ctx)
+
+	client, ok := lp.chatService.clients[caller.ClientId]
 	if !ok {
 		return nil, fmt.Errorf("%w: client (probably) already deleted", ty.ErrNotAvailable)
 	}
 
-	if client.groupId != "" {
-		delete(lp.chatService.groups[client.groupId].clients, client.ClientId)
-	}
-
-	fmt.Printf("\nlogged out %s", client.Name)
-	client.Close()
-	delete(lp.chatService.clients, client.ClientId)
+	fmt.Printf("\nlogged out %s", client.GetName())
+	lp.chatService.removeClientRequireLock(client)
 
-	go lp.chatService.Broadcast(nil, &ty.Response{RspName: ty.UserRemoveFlag, Content: msg.Name, ClientId: msg.ClientId})
+	go lp.chatService.Broadcast(nil, ty.UserLeftEvent(caller.ClientId, caller.Name))
 
-	return &ty.Response{RspName: msg.Name, Content: ty.UnregisterFlag}, nil
+	return ty.UnregisteredEvent(caller.Name), nil
 }
 
 // RegisterClientPlugin safely registeres a client by creating a Client with the received values
@@ -133,12 +155,14 @@ func (lp *LogOutPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
 type RegisterClientPlugin struct {
 	chatService *ChatService
 	pr          *PluginRegistry
+	accounts    *AccountStore
 }
 
-func NewRegisterClientPlugin(s *ChatService, pr *PluginRegistry) *RegisterClientPlugin {
+func NewRegisterClientPlugin(s *ChatService, pr *PluginRegistry, accounts *AccountStore) *RegisterClientPlugin {
 	return &RegisterClientPlugin{
 		chatService: s,
 		pr:          pr,
+		accounts:    accounts,
 	}
 }
 
@@ -149,40 +173,179 @@ func (rp *RegisterClientPlugin) Description() *Description {
 	}
 }
 
-func (rp *RegisterClientPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (rp *RegisterClientPlugin) CheckScope() int {
+	return UnregisteredOnly
+}
+
+func (rp *RegisterClientPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	name, err := parseName(msg.Name)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	if rp.accounts.Exists(name) {
+		return ty.ErrorEvent(fmt.Errorf("%w: the name %s belongs to an account, use /login", ty.ErrNoPermission, name)), nil
+	}
+
 	rp.chatService.mu.Lock()
 	defer rp.chatService.mu.Unlock()
 
-	if len(rp.chatService.clients) >= rp.chatService.maxUsers {
-		return &ty.Response{Err: fmt.Sprintf("%v: usercap %d reached, try again later. users:%d", ty.ErrNoPermission, rp.chatService.maxUsers, len(rp.chatService.clients))}, nil
+	return rp.chatService.addClientRequireLock(name, CallerFrom(ctx).ClientId, ""), nil
+}
+
+// SignupPlugin creates an account with a hashed password and logs the client in with it
+type SignupPlugin struct {
+	chatService *ChatService
+	accounts    *AccountStore
+}
+
+func NewSignupPlugin(s *ChatService, accounts *AccountStore) *SignupPlugin {
+	return &SignupPlugin{
+		chatService: s,
+		accounts:    accounts,
+	}
+}
+
+func (sp *SignupPlugin) Description() *Description {
+	return &Description{
+		Description: "creates an account and logs you in",
+		Template:    "/signup {name} {password}",
+	}
+}
+
+func (sp *SignupPlugin) CheckScope() int {
+	return UnregisteredOnly
+}
+
+func (sp *SignupPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	name, password, err := parseCredentials(msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	account, err := NewAccount(name, password)
+	if err != nil {
+		return nil, err
+	}
+
+	sp.chatService.mu.Lock()
+	defer sp.chatService.mu.Unlock()
+
+	if sp.accounts.Exists(name) {
+		return ty.ErrorEvent(fmt.Errorf("%w: the name %s is already taken", ty.ErrNoPermission, name)), nil
+	}
+
+	clientId := CallerFrom(ctx).ClientId
+
+	rsp := sp.chatService.addClientRequireLock(name, clientId, name)
+	if rsp.Error != nil {
+		return rsp, nil
+	}
+
+	// the account is only stored once its client is registered, so a failed signup leaves no account behind
+	err = sp.accounts.Add(account)
+	if err != nil {
+		sp.chatService.removeClientRequireLock(sp.chatService.clients[clientId])
+		go sp.chatService.Broadcast(nil, ty.UserLeftEvent(clientId, name))
+
+		return ty.ErrorEvent(err), nil
+	}
+
+	fmt.Printf("\nnew account '%s' created.", name)
+
+	return rsp, nil
+}
+
+// LoginPlugin logs a client in with the name and password of an existing account
+type LoginPlugin struct {
+	chatService *ChatService
+	accounts    *AccountStore
+}
+
+func NewLoginPlugin(s *ChatService, accounts *AccountStore) *LoginPlugin {
+	return &LoginPlugin{
+		chatService: s,
+		accounts:    accounts,
+	}
+}
+
+func (lp *LoginPlugin) Description() *Description {
+	return &Description{
+		Description: "logs you in with your account",
+		Template:    "/login {name} {password}",
+	}
+}
+
+func (lp *LoginPlugin) CheckScope() int {
+	return UnregisteredOnly
+}
+
+func (lp *LoginPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	name, password, err := parseCredentials(msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	account, err := lp.accounts.Authenticate(name, password)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	lp.chatService.mu.Lock()
+	defer lp.chatService.mu.Unlock()
+
+	if lp.chatService.loggedInRequireLock(account.Name) {
+		return ty.ErrorEvent(fmt.Errorf("%w: the account %s is already logged in", ty.ErrNoPermission, account.Name)), nil
+	}
+
+	return lp.chatService.addClientRequireLock(account.Name, CallerFrom(ctx).ClientId, account.Name), nil
+}
+
+// NickPlugin changes the name of a client, names of accounts can only be taken by their owner
+type NickPlugin struct {
+	chatService *ChatService
+	accounts    *AccountStore
+}
+
+func NewNickPlugin(s *ChatService, accounts *AccountStore) *NickPlugin {
+	return &NickPlugin{
+		chatService: s,
+		accounts:    accounts,
+	}
+}
+
+func (np *NickPlugin) Description() *Description {
+	return &Description{
+		Description: "changes your name",
+		Template:    "/nick {name}",
 	}
+}
 
-	if _, exists := rp.chatService.clients[msg.ClientId]; exists {
-		return &ty.Response{Err: fmt.Sprintf("%v: client already registered", ty.ErrNoPermission)}, nil
+func (np *NickPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (np *NickPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	name, err := parseName(msg.Content)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
 	}
 
-	clientCh := make(chan *ty.Response, 100)
-	token := ty.GenerateSecureToken(64)
-	client := &Client{
-		Name:      msg.Name,
-		ClientId:  msg.ClientId,
-		GroupName: "",
-		groupId:   "",
-		clientCh:  clientCh,
-		active:    true,
-		authToken: token,
-		lastSign:  time.Now().UTC(),
-		chClosed:  false,
-		rtcs:      make(map[string]string),
-		mu:        sync.RWMutex{},
+	client, err := np.chatService.GetClient(CallerFrom(ctx).ClientId)
+	if err != nil {
+		return nil, fmt.Errorf("%w: client (probably) already deleted", ty.ErrNotAvailable)
 	}
-	rp.chatService.clients[msg.ClientId] = client
 
-	fmt.Printf("\nnew client '%s' registered.", msg.Content)
+	if np.accounts.Exists(name) && !sameName(client.GetAccount(), name) {
+		return ty.ErrorEvent(fmt.Errorf("%w: the name %s belongs to an account", ty.ErrNoPermission, name)), nil
+	}
 
-	go rp.chatService.Broadcast(nil, &ty.Response{RspName: ty.UserAddFlag, Content: client.Name, ClientId: msg.ClientId})
+	rsp, err := np.chatService.RenameClient(client, name)
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
 
-	return &ty.Response{RspName: msg.Name, Content: token}, nil
+	return rsp, nil
 }
 
 // BroadcaastPlugin distributes an incomming message abroad all client channels if
@@ -197,32 +360,104 @@ func NewBroadcastPlugin(s *ChatService) *BroadcastPlugin {
 
 func (bp *BroadcastPlugin) Description() *Description {
 	return &Description{
-		Description: "sends a message in your current room",
+		Description: "sends a message in your active room or the group of the groupId",
 		Template:    "{message}",
 	}
 }
 
-func (bp *BroadcastPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	rsp := &ty.Response{RspName: msg.Name, Content: msg.Content, ClientId: msg.ClientId}
+func (bp *BroadcastPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (bp *BroadcastPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	caller := CallerFrom(ctx)
+	rsp := ty.ChatEvent(caller.Name, caller.ClientId, msg.Content, false)
 
 	if strings.TrimSpace(msg.Content) == "" {
 		return rsp, nil
 	}
 
-	group, _, err := GetCurrentGroup(msg.ClientId, bp.chatService)
+	group, _, err := GetTargetGroup(caller.ClientId, msg.GroupId, bp.chatService)
 	if err != nil {
-		return &ty.Response{Err: fmt.Sprintf("%v: error getting current group", err)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: error getting target group", err)), nil
 	}
 
 	if group != nil {
+		rsp.GroupId = group.GroupId
 		bp.chatService.Broadcast(group.GetClients(), rsp)
+		bp.chatService.Record(ty.JsonMessage{Scope: ty.GroupScope, GroupId: group.GroupId, Sender: caller.Name, SenderId: caller.ClientId, Content: msg.Content})
+
 		return rsp, nil
 	}
 
 	bp.chatService.Broadcast(nil, rsp)
+	bp.chatService.Record(ty.JsonMessage{Scope: ty.LobbyScope, Sender: caller.Name, SenderId: caller.ClientId, Content: msg.Content})
+
 	return rsp, nil
 }
 
+// HistoryPlugin replays the newest lobby messages and your private messages, with the
+// --lobby option only the lobby messages
+type HistoryPlugin struct {
+	chatService *ChatService
+}
+
+func NewHistoryPlugin(s *ChatService) *HistoryPlugin {
+	return &HistoryPlugin{chatService: s}
+}
+
+func (hp *HistoryPlugin) Description() *Description {
+	return &Description{
+		Description: "shows the last n lobby and private messages, only lobby messages with --lobby",
+		Template:    "/history [n] [--lobby]",
+	}
+}
+
+func (hp *HistoryPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (hp *HistoryPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	fields := strings.Fields(msg.Content)
+	lobbyOnly := len(fields) > 0 && fields[len(fields)-1] == "--lobby"
+	if lobbyOnly {
+		fields = fields[:len(fields)-1]
+	}
+
+	n, err := parseHistoryCount(strings.Join(fields, " "))
+	if err != nil {
+		return ty.ErrorEvent(err), nil
+	}
+
+	caller := CallerFrom(ctx)
+
+	// private messages of earlier sessions are found by the account, the clientId changes with every login
+	var account string
+	if client, err := hp.chatService.GetClient(caller.ClientId); err == nil {
+		account = client.GetAccount()
+	}
+
+	participates := func(clientId string, participant string) bool {
+		return clientId == caller.ClientId || (account != "" && sameName(participant, account))
+	}
+
+	messages, err := hp.chatService.History(n, func(m ty.JsonMessage) bool {
+		switch m.Scope {
+		case ty.LobbyScope:
+			return true
+		case ty.PrivateScope:
+			return !lobbyOnly && (participates(m.SenderId, m.SenderAccount) || participates(m.ReceiverId, m.ReceiverAccount))
+		}
+
+		return false
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: error reading message history", err)
+	}
+
+	return historyResponse(messages)
+}
+
 // HelpPlugin tells you information about available plugins
 type HelpPlugin struct {
 	pr *PluginRegistry
@@ -239,13 +474,12 @@ func (h *HelpPlugin) Description() *Description {
 	}
 }
 
-func (h *HelpPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	jsonList, err := json.Marshal(ListPlugins(h.pr.plugins))
-	if err != nil {
-		return nil, fmt.Errorf("%w: error parsing plugins to json", err)
-	}
+func (h *HelpPlugin) CheckScope() int {
+	return RegisteredOnly
+}
 
-	return &ty.Response{RspName: "Help", Content: string(jsonList)}, nil
+func (h *HelpPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	return ty.TableEvent("Help", ListPlugins(h.pr.plugins)), nil
 }
 
 // ListUsersPlugin tells you information about all the current users
@@ -264,18 +498,15 @@ func (u *ListUsersPlugin) Description() *Description {
 	}
 }
 
-func (u *ListUsersPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (u *ListUsersPlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (u *ListUsersPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
 	u.chatService.mu.RLock()
 	defer u.chatService.mu.RUnlock()
 
-	clientsSlice := ClientsToJsonSliceRequireLock(u.chatService.clients, msg.ClientId)
-
-	jsonList, err := json.Marshal(clientsSlice)
-	if err != nil {
-		return nil, fmt.Errorf("%w: error parsing clients to json", err)
-	}
-
-	return &ty.Response{RspName: ty.UsersFlag, Content: string(jsonList)}, nil
+	return ty.UsersEvent(ClientsToJsonSliceRequireLock(u.chatService.clients, CallerFrom(ctx).ClientId)), nil
 }
 
 // TimePlugin tells you the current time
@@ -292,6 +523,10 @@ func (t *TimePlugin) Description() *Description {
 	}
 }
 
-func (t *TimePlugin) Execute(msg *ty.Message) (*ty.Response, error) {
-	return &ty.Response{RspName: "Time", Content: time.Now().UTC().String()}, nil
+func (t *TimePlugin) CheckScope() int {
+	return RegisteredOnly
+}
+
+func (t *TimePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	return ty.ResultEvent("Time", time.Now().UTC().String()), nil
 }
@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestSignupAndLogin(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	rsp := execute(t, pr, ClientId, "/signup", ClientName+" "+Password)
+	assert.Nil(t, rsp.Error)
+	assert.NotEmpty(t, rsp.Content)
+
+	// the account can't be used by a second session at the same time, even after a rename
+	rsp = execute(t, pr, ClientId2, "/login", ClientName+" "+Password)
+	assert.ErrorIs(t, rsp.Error, ty.ErrNoPermission)
+
+	rsp = execute(t, pr, ClientId, "/nick", ClientName2)
+	assert.Nil(t, rsp.Error)
+
+	rsp = execute(t, pr, ClientId2, "/login", ClientName+" "+Password)
+	assert.ErrorIs(t, rsp.Error, ty.ErrNoPermission)
+
+	execute(t, pr, ClientId, "/quit", "")
+
+	rsp = execute(t, pr, ClientId2, "/login", ClientName+" "+Password)
+	assert.Nil(t, rsp.Error)
+
+	client, err := service.GetClient(ClientId2)
+	assert.Nil(t, err)
+	assert.Equal(t, ClientName, client.GetAccount())
+}
+
+func TestFailedSignupKeepsNoAccount(t *testing.T) {
+	_, pr := newTestService(t, func(cfg *config.Config) { cfg.MaxUsers = 1 })
+
+	register(t, pr, ClientId, ClientName)
+
+	rsp := execute(t, pr, ClientId2, "/signup", ClientName2+" "+Password)
+	assert.ErrorIs(t, rsp.Error, ty.ErrNoPermission)
+
+	execute(t, pr, ClientId, "/quit", "")
+
+	rsp = execute(t, pr, ClientId2, "/login", ClientName2+" "+Password)
+	assert.ErrorIs(t, rsp.Error, ty.ErrNoPermission)
+}
+
+func TestLobbyReachesClientsInGroups(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "create Room").Error)
+
+	client, err := service.GetClient(ClientId2)
+	assert.Nil(t, err)
+	drain(client)
+
+	// lobby messages and renames reach the client, although the group is its active room
+	assert.Nil(t, execute(t, pr, ClientId, "/broadcast", "hello").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/nick", ClientName3).Error)
+
+	rsps := receive(t, client, 2)
+	if assert.Len(t, rsps, 2) {
+		assert.Equal(t, ty.KindChat, rsps[0].Kind)
+		assert.Empty(t, rsps[0].GroupId)
+		assert.Equal(t, ty.KindUserRenamed, rsps[1].Kind)
+	}
+}
+
+func TestNameValidation(t *testing.T) {
+	_, pr := newTestService(t, nil)
+
+	// /register, /signup and /nick share the validation of the name
+	for _, name := range []string{"ab", "Len Kim", "@Len"} {
+		assert.ErrorIs(t, tryRegister(t, pr, ClientId2, name).Error, ty.ErrParsing, name)
+		assert.ErrorIs(t, execute(t, pr, ClientId2, "/signup", name+" "+Password).Error, ty.ErrParsing, name)
+	}
+
+	register(t, pr, ClientId, ClientName)
+
+	for _, name := range []string{"ab", "Len Kim", "@Len"} {
+		assert.ErrorIs(t, execute(t, pr, ClientId, "/nick", name).Error, ty.ErrParsing, name)
+	}
+}
+
+func TestNameUniqueness(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/signup", ClientName+" "+Password).Error)
+	execute(t, pr, ClientId, "/quit", "")
+
+	// the name of an account can't be taken by a guest, regardless of case and whitespace
+	for _, name := range []string{ClientName, strings.ToUpper(ClientName), ClientName + " ", " " + ClientName} {
+		assert.ErrorIs(t, tryRegister(t, pr, ClientId2, name).Error, ty.ErrNoPermission, name)
+	}
+
+	register(t, pr, ClientId2, ClientName2)
+
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/nick", strings.ToLower(ClientName)).Error, ty.ErrNoPermission)
+
+	// names of guests are unique as well
+	assert.ErrorIs(t, tryRegister(t, pr, ClientId3, strings.ToUpper(ClientName2)).Error, ty.ErrNoPermission)
+
+	register(t, pr, ClientId3, ClientName3)
+	assert.ErrorIs(t, execute(t, pr, ClientId3, "/nick", ClientName2+" ").Error, ty.ErrNoPermission)
+
+	// the owner of the account takes its name back
+	assert.Nil(t, execute(t, pr, ClientId4, "/login", ClientName+" "+Password).Error)
+	assert.Nil(t, execute(t, pr, ClientId4, "/nick", ClientName4).Error)
+	assert.Nil(t, execute(t, pr, ClientId4, "/nick", strings.ToLower(ClientName)).Error)
+
+	client, err := service.GetClient(ClientId4)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.ToLower(ClientName), client.GetName())
+}
+
+func TestLobbyHistory(t *testing.T) {
+	_, pr := newTestService(t, nil)
+
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/broadcast", "first").Error)
+	sendPrivate(t, pr, ClientId, ClientId2, "secret")
+	assert.Nil(t, execute(t, pr, ClientId, "/broadcast", "second").Error)
+
+	// private messages don't take the place of the replayed lobby messages
+	assert.Equal(t, []string{"first", "second"}, historyContents(t, execute(t, pr, ClientId2, "/history", "2 --lobby")))
+	assert.Equal(t, []string{"first", "second"}, historyContents(t, execute(t, pr, ClientId2, "/history", "--lobby")))
+	assert.Equal(t, []string{"secret", "second"}, historyContents(t, execute(t, pr, ClientId2, "/history", "2")))
+
+	assert.ErrorIs(t, execute(t, pr, ClientId2, "/history", "--lobby 2").Error, ty.ErrParsing)
+}
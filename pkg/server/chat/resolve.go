@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// AmbiguousNameError is returned if a name addresses several clients or groups,
+// the candidates are the ids the caller can choose from
+type AmbiguousNameError struct {
+	Name       string
+	Candidates []string
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("%v: %s is ambiguous, use one of the ids: %s", ty.ErrParsing, e.Name, strings.Join(e.Candidates, ", "))
+}
+
+func (e *AmbiguousNameError) Unwrap() error {
+	return ty.ErrParsing
+}
+
+func (e *AmbiguousNameError) ErrorDetails() map[string]string {
+	return map[string]string{"name": e.Name, "candidates": strings.Join(e.Candidates, ",")}
+}
+
+// sameName reports whether the names are equal regardless of case and repeated spaces
+func sameName(a string, b string) bool {
+	return strings.EqualFold(strings.Join(strings.Fields(a), " "), strings.Join(strings.Fields(b), " "))
+}
+
+// resolveClient returns the client of the clients addressed by its id or its name,
+// which may be prefixed with an @
+func resolveClient(clients map[string]*Client, target string) (*Client, error) {
+	target = strings.TrimPrefix(strings.TrimSpace(target), "@")
+	if target == "" {
+		return nil, fmt.Errorf("%w: expected {name} or {clientId}", ty.ErrParsing)
+	}
+
+	if client, exists := clients[target]; exists {
+		return client, nil
+	}
+
+	matches := []*Client{}
+	for _, client := range clients {
+		if sameName(client.GetName(), target) {
+			matches = append(matches, client)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: there is no client named %s", ty.ErrNotAvailable, target)
+	case 1:
+		return matches[0], nil
+	}
+
+	ids := []string{}
+	for _, client := range matches {
+		ids = append(ids, client.ClientId)
+	}
+
+	slices.Sort(ids)
+
+	return nil, &AmbiguousNameError{Name: target, Candidates: ids}
+}
+
+// ResolveClient returns the registered client addressed by its id or its name
+func (s *ChatService) ResolveClient(target string) (*Client, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return resolveClient(s.clients, target)
+}
+
+// ResolveGroup returns the group addressed by its id or its name. Names only address
+// public groups and those the client is a member of, invited into or keeps a role in,
+// so unlisted and private groups aren't revealed
+func (s *ChatService) ResolveGroup(clientId string, target string) (*Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("%w: expected {name} or {groupId}", ty.ErrParsing)
+	}
+
+	if group, exists := s.groups[target]; exists {
+		return group, nil
+	}
+
+	account := ""
+	if client, exists := s.clients[clientId]; exists {
+		account = client.GetAccount()
+	}
+
+	s.pruneInvitesRequireLock(time.Now().UTC())
+
+	matches := []*Group{}
+	for groupId, group := range s.groups {
+		_, invited := s.invites[clientId][groupId]
+		visible := group.Visibility == ty.PublicGroup || invited || group.Role(clientId) != "" || group.KeepsRole(account)
+
+		if visible && sameName(group.Name, target) {
+			matches = append(matches, group)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: there is no group named %s", ty.ErrNotAvailable, target)
+	case 1:
+		return matches[0], nil
+	}
+
+	ids := []string{}
+	for _, group := range matches {
+		ids = append(ids, group.GroupId)
+	}
+
+	slices.Sort(ids)
+
+	return nil, &AmbiguousNameError{Name: target, Candidates: ids}
+}
@@ -0,0 +1,94 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// scopes a plugin can require, they are checked by the registries before the plugin is executed.
+// The values are sent to clients in the details of a ScopeError, so they mustn't change
+const (
+	UnregisteredOnly   = 0
+	RegisteredOnly     = 1
+	InGroupOnly        = 2
+	GroupOwnerOnly     = 3
+	Always             = 5
+	GroupModeratorOnly = 6
+)
+
+// ScopeError is returned if the caller doesn't fulfill the scope required by a plugin
+type ScopeError struct {
+	Plugin string
+	Scope  int
+}
+
+func (e *ScopeError) Error() string {
+	reason := "you are not allowed to use this command"
+
+	switch e.Scope {
+	case UnregisteredOnly:
+		reason = "you are already registered"
+	case RegisteredOnly:
+		reason = "you are not registered yet"
+	case InGroupOnly:
+		reason = "you are not in a group yet"
+	case GroupModeratorOnly:
+		reason = "only moderators of the group can use this command"
+	case GroupOwnerOnly:
+		reason = "only the owner of the group can use this command"
+	}
+
+	return fmt.Sprintf("%v: %s (%s)", ty.ErrNoPermission, reason, e.Plugin)
+}
+
+func (e *ScopeError) Unwrap() error {
+	return ty.ErrNoPermission
+}
+
+func (e *ScopeError) ErrorDetails() map[string]string {
+	return map[string]string{"plugin": e.Plugin, "scope": strconv.Itoa(e.Scope)}
+}
+
+// checkScope returns a ScopeError if the caller of the context doesn't fulfill the scope, group
+// scopes are checked in the group addressed by groupId or in the active group if it is empty
+func checkScope(ctx context.Context, s *ChatService, plugin string, scope int, groupId string) error {
+	caller := CallerFrom(ctx)
+	allowed := true
+
+	switch scope {
+	case UnregisteredOnly:
+		allowed = !caller.Registered
+	case RegisteredOnly:
+		allowed = caller.Registered
+	case InGroupOnly, GroupModeratorOnly, GroupOwnerOnly:
+		allowed = false
+
+		if caller.Registered {
+			group, _, err := GetTargetGroup(caller.ClientId, groupId, s)
+			allowed = err == nil && group != nil && hasGroupScope(group.Role(caller.ClientId), scope)
+		}
+	}
+
+	if !allowed {
+		return &ScopeError{Plugin: plugin, Scope: scope}
+	}
+
+	return nil
+}
+
+// hasGroupScope reports whether the role in a group fulfills the group scope
+func hasGroupScope(role string, scope int) bool {
+	switch scope {
+	case InGroupOnly:
+		return role != ""
+	case GroupModeratorOnly:
+		return role == ty.ModeratorRole || role == ty.OwnerRole
+	case GroupOwnerOnly:
+		return role == ty.OwnerRole
+	}
+
+	return false
+}
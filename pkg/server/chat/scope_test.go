@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestCheckScope(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	// Arndt owns the group, Len moderates it, Kim is a member and Ole isn't in it
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+	register(t, pr, ClientId3, ClientName3)
+	register(t, pr, ClientId4, ClientName4)
+
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "create Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId2, "/group", "join Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId3, "/group", "join Room").Error)
+	assert.Nil(t, execute(t, pr, ClientId, "/group", "promote "+ClientName2).Error)
+
+	callers := map[string]Caller{"guest": {ClientId: "guest"}}
+	for role, clientId := range map[string]string{"registered": ClientId4, "member": ClientId3, "moderator": ClientId2, "owner": ClientId} {
+		client, err := service.GetClient(clientId)
+		assert.Nil(t, err)
+		callers[role] = client.Caller()
+	}
+
+	tests := []struct {
+		scope   int
+		allowed []string
+	}{
+		{UnregisteredOnly, []string{"guest"}},
+		{RegisteredOnly, []string{"registered", "member", "moderator", "owner"}},
+		{InGroupOnly, []string{"member", "moderator", "owner"}},
+		{GroupModeratorOnly, []string{"moderator", "owner"}},
+		{GroupOwnerOnly, []string{"owner"}},
+		{Always, []string{"guest", "registered", "member", "moderator", "owner"}},
+	}
+
+	for _, test := range tests {
+		for role, caller := range callers {
+			err := checkScope(WithCaller(context.Background(), caller), service, "/test", test.scope, "")
+
+			if slices.Contains(test.allowed, role) {
+				assert.Nil(t, err, "scope %d, caller %s", test.scope, role)
+				continue
+			}
+
+			var scopeErr *ScopeError
+			assert.ErrorAs(t, err, &scopeErr, "scope %d, caller %s", test.scope, role)
+			assert.ErrorIs(t, err, ty.ErrNoPermission)
+		}
+	}
+}
+
+func TestCheckClaim(t *testing.T) {
+	tests := []struct {
+		claimed  string
+		verified string
+		allowed  bool
+	}{
+		{"", ClientId, true},
+		{ClientId, "", true},
+		{ClientId, ClientId, true},
+		{ClientId2, ClientId, false},
+	}
+
+	for _, test := range tests {
+		err := checkClaim("clientId", test.claimed, test.verified)
+
+		if test.allowed {
+			assert.Nil(t, err)
+			continue
+		}
+
+		var identityErr *IdentityError
+		assert.ErrorAs(t, err, &identityErr)
+		assert.ErrorIs(t, err, ty.ErrNoPermission)
+	}
+}
+
+func TestSignalsRequireGroup(t *testing.T) {
+	service, pr := newTestService(t, nil)
+
+	register(t, pr, ClientId, ClientName)
+	register(t, pr, ClientId2, ClientName2)
+
+	client, err := service.GetClient(ClientId)
+	assert.Nil(t, err)
+
+	signal := &ty.Message{Plugin: "/" + ty.OfferSignalFlag, Name: ClientId, ClientId: ClientId2}
+	_, err = RegisterCallPlugins(service).FindAndExecute(WithCaller(context.Background(), client.Caller()), signal)
+
+	var scopeErr *ScopeError
+	assert.ErrorAs(t, err, &scopeErr)
+}
@@ -0,0 +1,91 @@
+package chat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	config "github.com/F4c3hugg3r/Go-Chat-Server/pkg/server/config"
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+var (
+	ClientName  = "Arndt"
+	ClientName2 = "Len"
+	ClientName3 = "Kim"
+	ClientName4 = "Ole"
+	ClientId    = "clientId-DyGWNnLrLWnbuhf-LgBUAdAxdZf-U1pgRw"
+	ClientId2   = "clientId2-yGWNnLrLWnbuhf-LgBUAdAxdZf-U1pgRw"
+	ClientId3   = "clientId3-GWNnLrLWnbuhf-LgBUAdAxdZf-U1pgRw"
+	ClientId4   = "clientId4-WNnLrLWnbuhf-LgBUAdAxdZf-U1pgRw"
+	Password    = "wubbalubbadubdub"
+)
+
+// newTestService creates a chat service with its plugins and in memory stores, modify may change the config first
+func newTestService(t *testing.T, modify func(cfg *config.Config)) (*ChatService, *PluginRegistry) {
+	cfg := config.Default()
+	if modify != nil {
+		modify(cfg)
+	}
+
+	holder := config.NewHolder(cfg)
+	service := NewChatService(holder, NewMemoryStore(100), NewTokenManager("", time.Minute))
+
+	accounts, err := NewAccountStore("")
+	assert.Nil(t, err)
+
+	return service, RegisterPlugins(service, accounts, holder)
+}
+
+// execute runs the plugin as the client, which is only identified by its id if it isn't registered
+func execute(t *testing.T, pr *PluginRegistry, clientId string, plugin string, content string) *ty.Response {
+	caller := Caller{ClientId: clientId}
+	if client, err := pr.s.GetClient(clientId); err == nil {
+		caller = client.Caller()
+	}
+
+	rsp, err := pr.FindAndExecute(WithCaller(context.Background(), caller), &ty.Message{Name: caller.Name, Plugin: plugin, Content: content, ClientId: clientId})
+	if err != nil {
+		return ty.ErrorEvent(err)
+	}
+
+	return rsp
+}
+
+// register registers a guest with the name and fails the test if it isn't possible
+func register(t *testing.T, pr *PluginRegistry, clientId string, name string) {
+	assert.Nil(t, tryRegister(t, pr, clientId, name).Error)
+}
+
+// tryRegister registers a guest with the name and returns the response
+func tryRegister(t *testing.T, pr *PluginRegistry, clientId string, name string) *ty.Response {
+	caller := WithCaller(context.Background(), Caller{ClientId: clientId})
+
+	rsp, err := pr.FindAndExecute(caller, &ty.Message{Name: name, Plugin: "/register", Content: name, ClientId: clientId})
+	assert.Nil(t, err)
+
+	return rsp
+}
+
+// groupNamed returns the group with the name and fails the test if there is none
+func groupNamed(t *testing.T, s *ChatService, name string) *Group {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, group := range s.groups {
+		if group.Name == name {
+			return group
+		}
+	}
+
+	t.Fatalf("there is no group %s", name)
+	return nil
+}
+
+// isMember reports whether the client is in the group
+func isMember(group *Group, clientId string) bool {
+	_, member := group.GetClients()[clientId]
+	return member
+}
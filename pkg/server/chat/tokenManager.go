@@ -0,0 +1,129 @@
+package chat
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+// TokenManager issues HMAC signed session tokens with an expiry and keeps a list
+// of revoked tokens until they would have expired anyway
+type TokenManager struct {
+	secret []byte
+	ttl    time.Duration
+	// key is the token id, value its expiry
+	revoked map[string]time.Time
+	mu      sync.RWMutex
+}
+
+// NewTokenManager creates a TokenManager signing with the given secret, if the secret
+// is empty a random one is generated, so the tokens only stay valid until a restart
+func NewTokenManager(secret string, ttl time.Duration) *TokenManager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+	}
+
+	return &TokenManager{secret: key, ttl: ttl, revoked: make(map[string]time.Time)}
+}
+
+// Issue creates a signed token for the client which expires after the ttl
+func (tm *TokenManager) Issue(clientId string) (string, error) {
+	now := time.Now().UTC()
+	claims := ty.TokenClaims{
+		ClientId:  clientId,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(tm.ttl).Unix(),
+		Id:        ty.GenerateSecureToken(16),
+	}
+
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("%w: error parsing token claims to json", err)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+
+	return payload + "." + tm.sign(payload), nil
+}
+
+// Verify checks the signature, expiry and revocation of the token and if it was issued for the client
+func (tm *TokenManager) Verify(token string, clientId string) (*ty.TokenClaims, error) {
+	claims, err := tm.verifySignature(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ClientId != clientId {
+		return nil, fmt.Errorf("%w: token was issued for another client", ty.ErrNoPermission)
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("%w: token expired", ty.ErrNoPermission)
+	}
+
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	if _, revoked := tm.revoked[claims.Id]; revoked {
+		return nil, fmt.Errorf("%w: token was revoked", ty.ErrNoPermission)
+	}
+
+	return claims, nil
+}
+
+// Revoke puts the token onto the revocation list, tokens with an invalid signature are ignored
+func (tm *TokenManager) Revoke(token string) error {
+	claims, err := tm.verifySignature(token)
+	if err != nil {
+		return err
+	}
+
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	tm.revoked[claims.Id] = time.Unix(claims.ExpiresAt, 0)
+	fmt.Printf("\nrevoked token of client %s", claims.ClientId)
+
+	return nil
+}
+
+// PruneRevoked removes expired tokens from the revocation list, they are rejected by their expiry anyway
+func (tm *TokenManager) PruneRevoked() {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	for id, expiry := range tm.revoked {
+		if time.Now().After(expiry) {
+			delete(tm.revoked, id)
+		}
+	}
+}
+
+// verifySignature compares the signature of the token in constant time and returns its claims
+func (tm *TokenManager) verifySignature(token string) (*ty.TokenClaims, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(tm.sign(payload))) {
+		return nil, fmt.Errorf("%w: invalid token signature", ty.ErrNoPermission)
+	}
+
+	return ty.ParseTokenClaims(token)
+}
+
+func (tm *TokenManager) sign(payload string) string {
+	mac := hmac.New(sha256.New, tm.secret)
+	mac.Write([]byte(payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
+)
+
+func TestTokenManager(t *testing.T) {
+	tm := NewTokenManager("secret", time.Hour)
+
+	token, err := tm.Issue(ClientId)
+	assert.Nil(t, err)
+
+	claims, err := tm.Verify(token, ClientId)
+	assert.Nil(t, err)
+	assert.Equal(t, ClientId, claims.ClientId)
+
+	_, err = tm.Verify(token, ClientId2)
+	assert.ErrorIs(t, err, ty.ErrNoPermission)
+
+	_, err = NewTokenManager("other secret", time.Hour).Verify(token, ClientId)
+	assert.ErrorIs(t, err, ty.ErrNoPermission)
+
+	_, err = tm.Verify(token+"x", ClientId)
+	assert.ErrorIs(t, err, ty.ErrNoPermission)
+
+	assert.Nil(t, tm.Revoke(token))
+	_, err = tm.Verify(token, ClientId)
+	assert.ErrorIs(t, err, ty.ErrNoPermission)
+
+	// other tokens of the client stay valid
+	token2, err := tm.Issue(ClientId)
+	assert.Nil(t, err)
+	_, err = tm.Verify(token2, ClientId)
+	assert.Nil(t, err)
+}
+
+func TestTokenManagerExpiry(t *testing.T) {
+	tm := NewTokenManager("", -time.Second)
+
+	token, err := tm.Issue(ClientId)
+	assert.Nil(t, err)
+
+	_, err = tm.Verify(token, ClientId)
+	assert.ErrorIs(t, err, ty.ErrNoPermission)
+
+	// expired tokens are pruned from the revocation list
+	assert.Nil(t, tm.Revoke(token))
+	tm.PruneRevoked()
+	assert.Empty(t, tm.revoked)
+}
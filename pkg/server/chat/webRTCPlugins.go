@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -8,7 +9,8 @@ import (
 )
 
 //
-// IMPORTANT NOTE: for WebRTC Signals, Message.Name represents the ownId and Message.ClientId represents the oppId
+// IMPORTANT NOTE: for WebRTC Signals, Message.Name represents the ownId and Message.ClientId represents the oppId,
+// the plugins read the ownId from the verified caller of the context only
 //
 
 // InitializeSignalPlugin initializes the rtc connection in the group and at the clients
@@ -20,9 +22,10 @@ func NewInitializeSignalPluginPlugin(s *ChatService) *InitializeSignalPlugin {
 	return &InitializeSignalPlugin{chatService: s}
 }
 
-func (isp *InitializeSignalPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (isp *InitializeSignalPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	ownId := CallerFrom(ctx).ClientId
 	fmt.Printf("\n[InitializeSignalPlugin] Execute called with msg: %+v", msg)
-	group, ownClient, err := GetCurrentGroup(msg.Name, isp.chatService)
+	group, ownClient, err := GetTargetGroup(ownId, msg.GroupId, isp.chatService)
 	if err != nil {
 		fmt.Printf("\n[InitializeSignalPlugin] Error getting current group: %v", err)
 		return nil, err
@@ -30,50 +33,51 @@ func (isp *InitializeSignalPlugin) Execute(msg *ty.Message) (*ty.Response, error
 	fmt.Printf("\n[InitializeSignalPlugin] Own client: %+v", ownClient)
 
 	if group == nil {
-		fmt.Printf("\n[InitializeSignalPlugin] Group is nil for msg.Name: %s", msg.Name)
+		fmt.Printf("\n[InitializeSignalPlugin] Group is nil for ownId: %s", ownId)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
+	msg.GroupId = group.GroupId
 
-	oppClient, err := isp.chatService.GetClient(msg.ClientId)
-	if err != nil {
-		fmt.Printf("\n[InitializeSignalPlugin] Error getting opposing client: %v", err)
-		return nil, fmt.Errorf("%w: error getting opposing client", err)
+	oppClient, exists := group.GetClients()[msg.ClientId]
+	if !exists {
+		fmt.Printf("\n[InitializeSignalPlugin] Opposing client %s is not in group %s", msg.ClientId, group.GroupId)
+		return nil, fmt.Errorf("%w: the opposing client is not in the group", ty.ErrNotAvailable)
 	}
 	fmt.Printf("\n[InitializeSignalPlugin] Opposing client: %+v", oppClient)
 
 	if strings.Contains(msg.Content, ty.CallAccepted) || strings.Contains(msg.Content, ty.CallDenied) {
 		fmt.Printf("\n[InitializeSignalPlugin] CallAccepted or CallDenied detected in content: %s", msg.Content)
-		err = isp.chatService.Echo(msg.ClientId, &ty.Response{RspName: ty.InitializeSignalFlag, ClientId: msg.Name, Content: msg.Content})
+		err = isp.chatService.Echo(msg.ClientId, groupSignal(group, ty.SignalEvent(ty.InitializeSignalFlag, ownId, msg.Content)))
 		if err != nil {
 			return nil, err
 		}
-		// err = oppClient.Send(&ty.Response{RspName: ty.InitializeSignalFlag, ClientId: msg.Name, Content: msg.Content})
+		// err = oppClient.Send(ty.SignalEvent(ty.InitializeSignalFlag, ownId, msg.Content))
 		ownClient.SetIsNegotiating(false)
 		oppClient.SetIsNegotiating(false)
 		return nil, nil
 	}
 
 	fmt.Printf("\n[InitializeSignalPlugin] checking if there is already a connection or negotiation process")
-	if group.CheckConnection(msg.Name, msg.ClientId) || ownClient.GetIsNegotiating() || oppClient.GetIsNegotiating() {
-		fmt.Printf("\n[InitializeSignalPlugin] There is already a connection or negotiation process between %s and %s", msg.Name, msg.ClientId)
+	if group.CheckConnection(ownId, msg.ClientId) || ownClient.GetIsNegotiating() || oppClient.GetIsNegotiating() {
+		fmt.Printf("\n[InitializeSignalPlugin] There is already a connection or negotiation process between %s and %s", ownId, msg.ClientId)
 		return nil, fmt.Errorf("%w: there is already a connection between or a negotiation process, please try again later", ty.ErrNoPermission)
 	}
 
-	fmt.Printf("\n[InitializeSignalPlugin] no current connectin found, connecting %s - %s", msg.Name, msg.ClientId)
-	group.SetConnection(msg.Name, msg.ClientId, false)
+	fmt.Printf("\n[InitializeSignalPlugin] no current connectin found, connecting %s - %s", ownId, msg.ClientId)
+	group.SetConnection(ownId, msg.ClientId, false)
 
 	err = ownClient.SetCallState(msg.ClientId, ty.OfferSignalFlag)
 	if err != nil {
 		return nil, err
 	}
 
-	err = oppClient.SetCallState(msg.Name, ty.AnswerSignalFlag)
+	err = oppClient.SetCallState(ownId, ty.AnswerSignalFlag)
 	if err != nil {
 		return nil, err
 	}
 
-	err = isp.chatService.Echo(msg.ClientId, &ty.Response{RspName: ty.InitializeSignalFlag, ClientId: msg.Name, Content: ty.ReceiveCall})
-	// err = oppClient.Send(&ty.Response{RspName: ty.InitializeSignalFlag, ClientId: msg.Name, Content: ty.ReceiveCall})
+	err = isp.chatService.Echo(msg.ClientId, groupSignal(group, ty.SignalEvent(ty.InitializeSignalFlag, ownId, ty.ReceiveCall)))
+	// err = oppClient.Send(ty.SignalEvent(ty.InitializeSignalFlag, ownId, ty.ReceiveCall))
 
 	ownClient.SetIsNegotiating(true)
 	oppClient.SetIsNegotiating(true)
@@ -90,18 +94,20 @@ func NewOfferSignalPlugin(s *ChatService) *OfferSignalPlugin {
 	return &OfferSignalPlugin{chatService: s}
 }
 
-func (osp *OfferSignalPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (osp *OfferSignalPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	ownId := CallerFrom(ctx).ClientId
 	fmt.Printf("\n[OfferSignalPlugin] Execute called with msg: %+v", msg)
-	group, ownClient, err := GetCurrentGroup(msg.Name, osp.chatService)
+	group, ownClient, err := GetTargetGroup(ownId, msg.GroupId, osp.chatService)
 	if err != nil {
 		fmt.Printf("\n[OfferSignalPlugin] Error getting current group: %v", err)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
 
 	if group == nil {
-		fmt.Printf("\n[OfferSignalPlugin] Group is nil for msg.Name: %s", msg.Name)
+		fmt.Printf("\n[OfferSignalPlugin] Group is nil for ownId: %s", ownId)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
+	msg.GroupId = group.GroupId
 
 	oppClient, err := osp.chatService.GetClient(msg.ClientId)
 	if err != nil {
@@ -117,16 +123,16 @@ func (osp *OfferSignalPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
 
 	if ownClient.GetCallState(msg.ClientId) != ty.StableSignalFlag &&
 		ownClient.GetCallState(msg.ClientId) != ty.OfferSignalFlag &&
-		oppClient.GetCallState(msg.Name) != ty.StableSignalFlag &&
-		oppClient.GetCallState(msg.Name) != ty.AnswerSignalFlag {
+		oppClient.GetCallState(ownId) != ty.StableSignalFlag &&
+		oppClient.GetCallState(ownId) != ty.AnswerSignalFlag {
 
-		fmt.Printf("\n[OfferSignalPlugin] Wrong call state: ownClient: %s, oppClient: %s", ownClient.GetCallState(msg.ClientId), oppClient.GetCallState(msg.Name))
+		fmt.Printf("\n[OfferSignalPlugin] Wrong call state: ownClient: %s, oppClient: %s", ownClient.GetCallState(msg.ClientId), oppClient.GetCallState(ownId))
 		return nil, fmt.Errorf("%w: Offer couldn't be sent, because ownclient %s or oppClient %s"+
-			"is in the wrong callState", ty.ErrNoPermission, ownClient.GetCallState(msg.ClientId), oppClient.GetCallState(msg.Name))
+			"is in the wrong callState", ty.ErrNoPermission, ownClient.GetCallState(msg.ClientId), oppClient.GetCallState(ownId))
 	}
 
-	fmt.Printf("\n[OfferSignalPlugin] Forwarding Offer from %s to %s", msg.Name, msg.ClientId)
-	osp.chatService.ForwardSignal(msg, ty.OfferSignalFlag)
+	fmt.Printf("\n[OfferSignalPlugin] Forwarding Offer from %s to %s", ownId, msg.ClientId)
+	osp.chatService.ForwardSignal(ownId, msg, ty.OfferSignalFlag)
 
 	return nil, err
 }
@@ -140,17 +146,19 @@ func NewAnswerSignalPlugin(s *ChatService) *AnswerSignalPlugin {
 	return &AnswerSignalPlugin{chatService: s}
 }
 
-func (asp *AnswerSignalPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (asp *AnswerSignalPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	ownId := CallerFrom(ctx).ClientId
 	fmt.Printf("\n[AnswerSignalPlugin] Execute called with msg: %+v", msg)
-	group, ownClient, err := GetCurrentGroup(msg.Name, asp.chatService)
+	group, ownClient, err := GetTargetGroup(ownId, msg.GroupId, asp.chatService)
 	if err != nil {
 		fmt.Printf("\n[AnswerSignalPlugin] Error getting current group: %v", err)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
 	if group == nil {
-		fmt.Printf("\n[AnswerSignalPlugin] Group is nil for msg.Name: %s", msg.Name)
+		fmt.Printf("\n[AnswerSignalPlugin] Group is nil for ownId: %s", ownId)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
+	msg.GroupId = group.GroupId
 
 	err = ownClient.SetCallState(msg.ClientId, ty.AnswerSignalFlag)
 	if err != nil {
@@ -176,8 +184,8 @@ func (asp *AnswerSignalPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
 			"is in the wrong callState %s", ty.ErrNoPermission, ownClient.GetCallState(msg.ClientId))
 	}
 
-	fmt.Printf("\n[AnswerSignalPlugin] Forwarding answer signal between %s and %s", msg.Name, msg.ClientId)
-	asp.chatService.ForwardSignal(msg, ty.AnswerSignalFlag)
+	fmt.Printf("\n[AnswerSignalPlugin] Forwarding answer signal between %s and %s", ownId, msg.ClientId)
+	asp.chatService.ForwardSignal(ownId, msg, ty.AnswerSignalFlag)
 
 	return nil, nil
 }
@@ -191,26 +199,28 @@ func NewICECandidatePlugin(s *ChatService) *ICECandidatePlugin {
 	return &ICECandidatePlugin{chatService: s}
 }
 
-func (ice *ICECandidatePlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (ice *ICECandidatePlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	ownId := CallerFrom(ctx).ClientId
 	fmt.Printf("\n[ICECandidatePlugin] Execute called with msg: %+v", msg)
-	group, _, err := GetCurrentGroup(msg.Name, ice.chatService)
+	group, _, err := GetTargetGroup(ownId, msg.GroupId, ice.chatService)
 	if err != nil {
 		fmt.Printf("\n[ICECandidatePlugin] Error getting current group: %v", err)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
 
 	if group == nil {
-		fmt.Printf("\n[ICECandidatePlugin] Group is nil for msg.Name: %s", msg.Name)
+		fmt.Printf("\n[ICECandidatePlugin] Group is nil for ownId: %s", ownId)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
+	msg.GroupId = group.GroupId
 
-	if !group.CheckConnection(msg.Name, msg.ClientId) {
-		fmt.Printf("\n[ICECandidatePlugin] No registered connection between %s and %s", msg.Name, msg.ClientId)
+	if !group.CheckConnection(ownId, msg.ClientId) {
+		fmt.Printf("\n[ICECandidatePlugin] No registered connection between %s and %s", ownId, msg.ClientId)
 		return nil, fmt.Errorf("%w: offer couldn't be sent because there is no registered connection", ty.ErrNotAvailable)
 	}
 
-	fmt.Printf("\n[ICECandidatePlugin] Forwarding ICE candidate between %s and %s", msg.Name, msg.ClientId)
-	ice.chatService.ForwardSignal(msg, ty.ICECandidateFlag)
+	fmt.Printf("\n[ICECandidatePlugin] Forwarding ICE candidate between %s and %s", ownId, msg.ClientId)
+	ice.chatService.ForwardSignal(ownId, msg, ty.ICECandidateFlag)
 
 	return nil, nil
 }
@@ -225,9 +235,10 @@ func NewStableSignalPlugin(s *ChatService) *StableSignalPlugin {
 	return &StableSignalPlugin{chatService: s}
 }
 
-func (ssp *StableSignalPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (ssp *StableSignalPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	ownId := CallerFrom(ctx).ClientId
 	fmt.Printf("\n[StableSignalPlugin] Execute called with msg: %+v", msg)
-	ownClient, err := ssp.chatService.GetClient(msg.Name)
+	ownClient, err := ssp.chatService.GetClient(ownId)
 	if err != nil {
 		fmt.Printf("\n[StableSignalPlugin] Error getting current group: %v", err)
 		return nil, fmt.Errorf("%w: error getting current group", err)
@@ -247,22 +258,24 @@ func NewConnectedPlugin(s *ChatService) *ConnectedPlugin {
 	return &ConnectedPlugin{chatService: s}
 }
 
-func (cp *ConnectedPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (cp *ConnectedPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	ownId := CallerFrom(ctx).ClientId
 	fmt.Printf("\n[ConnectedPlugin] Execute called with msg: %+v", msg)
-	group, ownClient, err := GetCurrentGroup(msg.Name, cp.chatService)
+	group, ownClient, err := GetTargetGroup(ownId, msg.GroupId, cp.chatService)
 	if err != nil {
 		fmt.Printf("\n[ConnectedPlugin] Error getting current group: %v", err)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
 
 	if group == nil {
-		fmt.Printf("\n[ConnectedPlugin] Group is nil for msg.Name: %s", msg.Name)
+		fmt.Printf("\n[ConnectedPlugin] Group is nil for ownId: %s", ownId)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
+	msg.GroupId = group.GroupId
 
 	fmt.Printf("\n[ConnectedPlugin] Setting call state to ConnectedFlag for client %s and establishing connection", msg.ClientId)
 	err = ownClient.SetCallState(msg.ClientId, ty.ConnectedFlag)
-	group.SetConnection(msg.Name, msg.ClientId, true)
+	group.SetConnection(ownId, msg.ClientId, true)
 
 	return nil, err
 }
@@ -276,16 +289,17 @@ func NewFailedConnectionPlugin(s *ChatService) *FailedConnectionPlugin {
 	return &FailedConnectionPlugin{chatService: s}
 }
 
-func (fcp *FailedConnectionPlugin) Execute(msg *ty.Message) (*ty.Response, error) {
+func (fcp *FailedConnectionPlugin) Execute(ctx context.Context, msg *ty.Message) (*ty.Response, error) {
+	ownId := CallerFrom(ctx).ClientId
 	fmt.Printf("\n[FailedConnectionPlugin] Execute called with msg: %+v", msg)
-	group, ownClient, err := GetCurrentGroup(msg.Name, fcp.chatService)
+	group, ownClient, err := GetTargetGroup(ownId, msg.GroupId, fcp.chatService)
 	if err != nil {
 		fmt.Printf("\n[FailedConnectionPlugin] Error getting current group: %v", err)
 		return nil, fmt.Errorf("%w: error getting current group", err)
 	}
 
 	if msg.ClientId == "" {
-		fmt.Printf("\n[FailedConnectionPlugin] ClientId is empty, removing unconnected RTCs for %s", msg.Name)
+		fmt.Printf("\n[FailedConnectionPlugin] ClientId is empty, removing unconnected RTCs for %s", ownId)
 		ownClient.RemoveUnconnectedRTCs()
 		return nil, nil
 	}
@@ -297,21 +311,31 @@ func (fcp *FailedConnectionPlugin) Execute(msg *ty.Message) (*ty.Response, error
 	}
 
 	if msg.Content == ty.RollbackDoneFlag {
-		fmt.Printf("\n[FailedConnectionPlugin] Rollback done for %s and %s", msg.Name, msg.ClientId)
+		fmt.Printf("\n[FailedConnectionPlugin] Rollback done for %s and %s", ownId, msg.ClientId)
 		if group != nil {
-			group.RemoveConnection(msg.Name, msg.ClientId, false)
+			group.RemoveConnection(ownId, msg.ClientId, false)
 		}
 		ownClient.RemoveRTC(msg.ClientId)
-		oppClient.RemoveRTC(msg.Name)
+		oppClient.RemoveRTC(ownId)
 		ownClient.SetIsNegotiating(false)
 		oppClient.SetIsNegotiating(false)
 
 		return nil, nil
 	}
 
-	fmt.Printf("\n[FailedConnectionPlugin] Echoing failed connection to %s and %s", msg.Name, msg.ClientId)
-	fcp.chatService.Echo(msg.Name, &ty.Response{ClientId: msg.ClientId, RspName: ty.FailedConnectionFlag, Content: ty.FailedConnectionFlag})
-	fcp.chatService.Echo(msg.ClientId, &ty.Response{ClientId: msg.Name, RspName: ty.FailedConnectionFlag, Content: ty.FailedConnectionFlag})
+	fmt.Printf("\n[FailedConnectionPlugin] Echoing failed connection to %s and %s", ownId, msg.ClientId)
+	fcp.chatService.Echo(ownId, groupSignal(group, ty.SignalEvent(ty.FailedConnectionFlag, msg.ClientId, ty.FailedConnectionFlag)))
+	fcp.chatService.Echo(msg.ClientId, groupSignal(group, ty.SignalEvent(ty.FailedConnectionFlag, ownId, ty.FailedConnectionFlag)))
 
 	return nil, nil
 }
+
+// groupSignal stamps the signal with the group of the call, so clients in several
+// groups know which call it belongs to
+func groupSignal(group *Group, rsp *ty.Response) *ty.Response {
+	if group != nil {
+		rsp.GroupId = group.GroupId
+	}
+
+	return rsp
+}
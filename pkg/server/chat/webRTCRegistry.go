@@ -1,22 +1,24 @@
 package chat
 
 import (
+	"context"
 	"fmt"
 
 	ty "github.com/F4c3hugg3r/Go-Chat-Server/pkg/shared"
 )
 
 type WebRTCInterface interface {
-	Execute(message *ty.Message) (*ty.Response, error)
+	Execute(ctx context.Context, message *ty.Message) (*ty.Response, error)
 }
 
 type WebRTCRegistry struct {
 	plugins map[string]WebRTCInterface
+	s       *ChatService
 }
 
 // RegisterPlugins sets up all the plugins
 func RegisterCallPlugins(chatService *ChatService) *WebRTCRegistry {
-	cr := &WebRTCRegistry{plugins: make(map[string]WebRTCInterface)}
+	cr := &WebRTCRegistry{plugins: make(map[string]WebRTCInterface), s: chatService}
 	cr.plugins[fmt.Sprint("/", ty.InitializeSignalFlag)] = NewInitializeSignalPluginPlugin(chatService)
 	cr.plugins[fmt.Sprint("/", ty.OfferSignalFlag)] = NewOfferSignalPlugin(chatService)
 	cr.plugins[fmt.Sprint("/", ty.AnswerSignalFlag)] = NewAnswerSignalPlugin(chatService)
@@ -28,11 +30,32 @@ func RegisterCallPlugins(chatService *ChatService) *WebRTCRegistry {
 	return cr
 }
 
-func (pr *WebRTCRegistry) FindAndExecute(message *ty.Message) (*ty.Response, error) {
+// FindAndExecute executes the call plugin of the signal as the caller of the context, signals
+// claiming another ownId are rejected with an IdentityError and callers outside of the group
+// of the signal with a ScopeError
+func (pr *WebRTCRegistry) FindAndExecute(ctx context.Context, message *ty.Message) (*ty.Response, error) {
 	plugin, ok := pr.plugins[message.Plugin]
 	if !ok {
-		return &ty.Response{Err: fmt.Sprintf("%v: no such call plugin found: %s", ty.ErrNoPermission, message.Plugin)}, nil
+		return ty.ErrorEvent(fmt.Errorf("%w: no such call plugin found: %s", ty.ErrNoPermission, message.Plugin)), nil
 	}
 
-	return plugin.Execute(message)
+	// calls only take place between members of a group
+	err := checkScope(ctx, pr.s, message.Plugin, InGroupOnly, message.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Message.Name carries the ownId of signals
+	err = checkClaim("name", message.Name, CallerFrom(ctx).ClientId)
+	if err != nil {
+		return nil, err
+	}
+
+	return plugin.Execute(ctx, message)
+}
+
+// Contains reports whether there is a call plugin registered for the given command
+func (pr *WebRTCRegistry) Contains(plugin string) bool {
+	_, ok := pr.plugins[plugin]
+	return ok
 }
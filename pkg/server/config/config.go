@@ -0,0 +1,505 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Duration is a time.Duration which is written as string like "10s" in the config file
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return fmt.Errorf("%w: duration has to be a string like \"10s\"", err)
+	}
+
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+// policies for chat responses of a client whose channel is full
+const (
+	DropOldest = "dropOldest"
+	DropNewest = "dropNewest"
+)
+
+// Config contains every setting of the server
+type Config struct {
+	Port          int    `json:"port"`
+	MaxUsers      int    `json:"maxUsers"`
+	MaxBatch      int    `json:"maxBatch"`
+	HistoryFile   string `json:"historyFile"`
+	HistorySize   int    `json:"historySize"`
+	AccountsFile  string `json:"accountsFile"`
+	GroupsFile    string `json:"groupsFile"`
+	TokenSecret   string `json:"tokenSecret"`
+	AdminKey      string `json:"adminKey"`
+	TLSCert       string `json:"tlsCert"`
+	TLSKey        string `json:"tlsKey"`
+	TLSSelfSigned bool   `json:"tlsSelfSigned"`
+	// size of the channel queuing responses of a client
+	ChannelBuffer int `json:"channelBuffer"`
+	// DropOldest or DropNewest chat response if the channel is full
+	ChatPolicy string `json:"chatPolicy"`
+	// maximum backlog of signaling and membership responses, which are never dropped,
+	// before a client is evicted as slow consumer
+	PriorityBuffer int `json:"priorityBuffer"`
+	// chat responses dropped in a row before a client is evicted, 0 disables it
+	SlowConsumerDrops int `json:"slowConsumerDrops"`
+	// maximum group size to start a call
+	MaxCallSize int `json:"maxCallSize"`
+	// maximum size of request bodies and websocket frames
+	MaxBodyBytes int64 `json:"maxBodyBytes"`
+	// requests per second and burst of the rate limits, a rate of 0 disables the limit.
+	// registrations are limited per ip, the other budgets per client
+	RegisterRate  float64 `json:"registerRate"`
+	RegisterBurst int     `json:"registerBurst"`
+	ChatRate      float64 `json:"chatRate"`
+	ChatBurst     int     `json:"chatBurst"`
+	CommandRate   float64 `json:"commandRate"`
+	CommandBurst  int     `json:"commandBurst"`
+	SignalRate    float64 `json:"signalRate"`
+	SignalBurst   int     `json:"signalBurst"`
+	// budget of opening streams, resuming sessions and refreshing tokens, so reconnects don't use up the command budget
+	StreamRate  float64 `json:"streamRate"`
+	StreamBurst int     `json:"streamBurst"`
+	// budget of polling the chat endpoint
+	PollRate  float64 `json:"pollRate"`
+	PollBurst int     `json:"pollBurst"`
+	// budget of admin requests per ip, so the admin key can't be guessed quickly
+	AdminRate  float64 `json:"adminRate"`
+	AdminBurst int     `json:"adminBurst"`
+
+	TimeLimit         Duration `json:"timeLimit"`
+	GracePeriod       Duration `json:"gracePeriod"`
+	TokenTTL          Duration `json:"tokenTTL"`
+	PollTimeout       Duration `json:"pollTimeout"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	WriteTimeout      Duration `json:"writeTimeout"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	CleanupInterval   Duration `json:"cleanupInterval"`
+	// time until a pending group invitation expires
+	InviteTimeout Duration `json:"inviteTimeout"`
+	// names nobody can register or take with /nick, compared regardless of case
+	ReservedNames []string `json:"reservedNames"`
+}
+
+// Default returns the config the server used before it was configurable
+func Default() *Config {
+	return &Config{
+		Port:              8080,
+		MaxUsers:          100,
+		MaxBatch:          50,
+		HistorySize:       1000,
+		ChannelBuffer:     100,
+		ChatPolicy:        DropOldest,
+		PriorityBuffer:    1000,
+		SlowConsumerDrops: 500,
+		MaxCallSize:       6,
+		MaxBodyBytes:      1 << 20,
+		RegisterRate:      0.2,
+		RegisterBurst:     5,
+		ChatRate:          2,
+		ChatBurst:         10,
+		CommandRate:       1,
+		CommandBurst:      10,
+		SignalRate:        50,
+		SignalBurst:       200,
+		StreamRate:        0.5,
+		StreamBurst:       10,
+		PollRate:          5,
+		PollBurst:         20,
+		AdminRate:         1,
+		AdminBurst:        10,
+		TimeLimit:         Duration(10 * time.Second),
+		GracePeriod:       Duration(2 * time.Minute),
+		TokenTTL:          Duration(15 * time.Minute),
+		PollTimeout:       Duration(10 * time.Second),
+		ReadTimeout:       Duration(15 * time.Second),
+		WriteTimeout:      Duration(15 * time.Second),
+		ReadHeaderTimeout: Duration(15 * time.Second),
+		CleanupInterval:   Duration(15 * time.Second),
+		InviteTimeout:     Duration(2 * time.Minute),
+		ReservedNames:     []string{"admin", "server", "system", "lobby"},
+	}
+}
+
+// Load reads the json config file at path over the defaults, applies the CHAT_* environment
+// variables and then the overrides, which are keyed by the json name of the setting, and
+// validates the result. If path is empty the defaults are used as base
+func Load(path string, overrides map[string]string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: config file couldn't be read", err)
+		}
+
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.DisallowUnknownFields()
+
+		err = decoder.Decode(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: config file couldn't be parsed", err)
+		}
+	}
+
+	for _, setting := range cfg.settings() {
+		value, ok := os.LookupEnv(envName(setting.key))
+		if !ok {
+			continue
+		}
+
+		err := setting.set(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: environment variable %s couldn't be parsed", err, envName(setting.key))
+		}
+	}
+
+	for key, value := range overrides {
+		err := cfg.Set(key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cfg, cfg.Validate()
+}
+
+// Set parses the value into the setting with the given json name
+func (cfg *Config) Set(key string, value string) error {
+	for _, setting := range cfg.settings() {
+		if setting.key != key {
+			continue
+		}
+
+		err := setting.set(value)
+		if err != nil {
+			return fmt.Errorf("%w: setting %s couldn't be parsed", err, key)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown setting %s", key)
+}
+
+// envName converts the json name of a setting into its environment variable, e.g. maxUsers into CHAT_MAX_USERS
+func envName(key string) string {
+	var name strings.Builder
+	name.WriteString("CHAT")
+
+	for i, r := range key {
+		if i == 0 || unicode.IsUpper(r) && !unicode.IsUpper(rune(key[i-1])) {
+			name.WriteByte('_')
+		}
+
+		name.WriteRune(unicode.ToUpper(r))
+	}
+
+	return name.String()
+}
+
+// Validate checks if every setting is in a usable range
+func (cfg *Config) Validate() error {
+	var errs []error
+
+	if cfg.Port < 1 || cfg.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port has to be between 1 and 65535"))
+	}
+
+	for name, value := range map[string]int64{
+		"maxUsers":       int64(cfg.MaxUsers),
+		"maxBatch":       int64(cfg.MaxBatch),
+		"channelBuffer":  int64(cfg.ChannelBuffer),
+		"priorityBuffer": int64(cfg.PriorityBuffer),
+		"maxCallSize":    int64(cfg.MaxCallSize),
+		"maxBodyBytes":   cfg.MaxBodyBytes,
+		"registerBurst":  int64(cfg.RegisterBurst),
+		"chatBurst":      int64(cfg.ChatBurst),
+		"commandBurst":   int64(cfg.CommandBurst),
+		"signalBurst":    int64(cfg.SignalBurst),
+		"streamBurst":    int64(cfg.StreamBurst),
+		"pollBurst":      int64(cfg.PollBurst),
+		"adminBurst":     int64(cfg.AdminBurst),
+	} {
+		if value < 1 {
+			errs = append(errs, fmt.Errorf("%s has to be positive", name))
+		}
+	}
+
+	for name, value := range map[string]float64{
+		"registerRate": cfg.RegisterRate,
+		"chatRate":     cfg.ChatRate,
+		"commandRate":  cfg.CommandRate,
+		"signalRate":   cfg.SignalRate,
+		"streamRate":   cfg.StreamRate,
+		"pollRate":     cfg.PollRate,
+		"adminRate":    cfg.AdminRate,
+	} {
+		if value < 0 {
+			errs = append(errs, fmt.Errorf("%s mustn't be negative", name))
+		}
+	}
+
+	if cfg.ChatPolicy != DropOldest && cfg.ChatPolicy != DropNewest {
+		errs = append(errs, fmt.Errorf("chatPolicy has to be %s or %s", DropOldest, DropNewest))
+	}
+
+	if cfg.SlowConsumerDrops < 0 {
+		errs = append(errs, fmt.Errorf("slowConsumerDrops mustn't be negative"))
+	}
+
+	if cfg.HistorySize < 0 {
+		errs = append(errs, fmt.Errorf("historySize mustn't be negative"))
+	}
+
+	for name, value := range map[string]Duration{
+		"timeLimit":         cfg.TimeLimit,
+		"tokenTTL":          cfg.TokenTTL,
+		"pollTimeout":       cfg.PollTimeout,
+		"readTimeout":       cfg.ReadTimeout,
+		"writeTimeout":      cfg.WriteTimeout,
+		"readHeaderTimeout": cfg.ReadHeaderTimeout,
+		"cleanupInterval":   cfg.CleanupInterval,
+		"inviteTimeout":     cfg.InviteTimeout,
+	} {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s has to be positive", name))
+		}
+	}
+
+	if cfg.GracePeriod < 0 {
+		errs = append(errs, fmt.Errorf("gracePeriod mustn't be negative"))
+	}
+
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		errs = append(errs, fmt.Errorf("tlsCert and tlsKey have to be set together"))
+	}
+
+	err := errors.Join(errs...)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	return nil
+}
+
+// Reloaded returns a copy of the config with the settings of next, which can safely be changed
+// while the server is running. Listener, TLS, token and storage settings are kept
+func (cfg *Config) Reloaded(next *Config) *Config {
+	reloaded := *cfg
+
+	reloaded.MaxUsers = next.MaxUsers
+	reloaded.MaxBatch = next.MaxBatch
+	reloaded.ChannelBuffer = next.ChannelBuffer
+	reloaded.ChatPolicy = next.ChatPolicy
+	reloaded.PriorityBuffer = next.PriorityBuffer
+	reloaded.SlowConsumerDrops = next.SlowConsumerDrops
+	reloaded.MaxCallSize = next.MaxCallSize
+	reloaded.MaxBodyBytes = next.MaxBodyBytes
+	reloaded.RegisterRate = next.RegisterRate
+	reloaded.RegisterBurst = next.RegisterBurst
+	reloaded.ChatRate = next.ChatRate
+	reloaded.ChatBurst = next.ChatBurst
+	reloaded.CommandRate = next.CommandRate
+	reloaded.CommandBurst = next.CommandBurst
+	reloaded.SignalRate = next.SignalRate
+	reloaded.SignalBurst = next.SignalBurst
+	reloaded.StreamRate = next.StreamRate
+	reloaded.StreamBurst = next.StreamBurst
+	reloaded.PollRate = next.PollRate
+	reloaded.PollBurst = next.PollBurst
+	reloaded.AdminRate = next.AdminRate
+	reloaded.AdminBurst = next.AdminBurst
+	reloaded.TimeLimit = next.TimeLimit
+	reloaded.GracePeriod = next.GracePeriod
+	reloaded.PollTimeout = next.PollTimeout
+	reloaded.InviteTimeout = next.InviteTimeout
+	reloaded.CleanupInterval = next.CleanupInterval
+	reloaded.ReservedNames = next.ReservedNames
+	reloaded.AdminKey = next.AdminKey
+
+	return &reloaded
+}
+
+// Holder keeps the current config, which can be replaced while the server is running
+type Holder struct {
+	current atomic.Pointer[Config]
+}
+
+func NewHolder(cfg *Config) *Holder {
+	h := &Holder{}
+	h.current.Store(cfg)
+
+	return h
+}
+
+// Get returns the current config, it mustn't be modified
+func (h *Holder) Get() *Config {
+	return h.current.Load()
+}
+
+// Update replaces the current config with a modified copy of it
+func (h *Holder) Update(modify func(cfg *Config)) {
+	for {
+		old := h.current.Load()
+		next := *old
+		modify(&next)
+
+		if h.current.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// Reload loads the config file at path again and applies its reloadable settings
+func (h *Holder) Reload(path string, overrides map[string]string) error {
+	next, err := Load(path, overrides)
+	if err != nil {
+		return err
+	}
+
+	h.Update(func(cfg *Config) {
+		*cfg = *cfg.Reloaded(next)
+	})
+
+	return nil
+}
+
+// setting is a config value which can be set by its json name from a string
+type setting struct {
+	key string
+	set func(value string) error
+}
+
+func (cfg *Config) settings() []setting {
+	return []setting{
+		intSetting("port", &cfg.Port),
+		intSetting("maxUsers", &cfg.MaxUsers),
+		intSetting("maxBatch", &cfg.MaxBatch),
+		stringSetting("historyFile", &cfg.HistoryFile),
+		intSetting("historySize", &cfg.HistorySize),
+		stringSetting("accountsFile", &cfg.AccountsFile),
+		stringSetting("groupsFile", &cfg.GroupsFile),
+		stringSetting("tokenSecret", &cfg.TokenSecret),
+		stringSetting("adminKey", &cfg.AdminKey),
+		stringSetting("tlsCert", &cfg.TLSCert),
+		stringSetting("tlsKey", &cfg.TLSKey),
+		{key: "tlsSelfSigned", set: func(value string) (err error) {
+			cfg.TLSSelfSigned, err = strconv.ParseBool(value)
+			return err
+		}},
+		intSetting("channelBuffer", &cfg.ChannelBuffer),
+		stringSetting("chatPolicy", &cfg.ChatPolicy),
+		intSetting("priorityBuffer", &cfg.PriorityBuffer),
+		intSetting("slowConsumerDrops", &cfg.SlowConsumerDrops),
+		intSetting("maxCallSize", &cfg.MaxCallSize),
+		{key: "maxBodyBytes", set: func(value string) (err error) {
+			cfg.MaxBodyBytes, err = strconv.ParseInt(value, 10, 64)
+			return err
+		}},
+		floatSetting("registerRate", &cfg.RegisterRate),
+		intSetting("registerBurst", &cfg.RegisterBurst),
+		floatSetting("chatRate", &cfg.ChatRate),
+		intSetting("chatBurst", &cfg.ChatBurst),
+		floatSetting("commandRate", &cfg.CommandRate),
+		intSetting("commandBurst", &cfg.CommandBurst),
+		floatSetting("signalRate", &cfg.SignalRate),
+		intSetting("signalBurst", &cfg.SignalBurst),
+		floatSetting("streamRate", &cfg.StreamRate),
+		intSetting("streamBurst", &cfg.StreamBurst),
+		floatSetting("pollRate", &cfg.PollRate),
+		intSetting("pollBurst", &cfg.PollBurst),
+		floatSetting("adminRate", &cfg.AdminRate),
+		intSetting("adminBurst", &cfg.AdminBurst),
+		durationSetting("timeLimit", &cfg.TimeLimit),
+		durationSetting("gracePeriod", &cfg.GracePeriod),
+		durationSetting("tokenTTL", &cfg.TokenTTL),
+		durationSetting("pollTimeout", &cfg.PollTimeout),
+		durationSetting("readTimeout", &cfg.ReadTimeout),
+		durationSetting("writeTimeout", &cfg.WriteTimeout),
+		durationSetting("readHeaderTimeout", &cfg.ReadHeaderTimeout),
+		durationSetting("cleanupInterval", &cfg.CleanupInterval),
+		durationSetting("inviteTimeout", &cfg.InviteTimeout),
+		listSetting("reservedNames", &cfg.ReservedNames),
+	}
+}
+
+func intSetting(key string, target *int) setting {
+	return setting{key: key, set: func(value string) error {
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+
+		*target = number
+		return nil
+	}}
+}
+
+func floatSetting(key string, target *float64) setting {
+	return setting{key: key, set: func(value string) error {
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+
+		*target = number
+		return nil
+	}}
+}
+
+func stringSetting(key string, target *string) setting {
+	return setting{key: key, set: func(value string) error {
+		*target = value
+		return nil
+	}}
+}
+
+// listSetting parses a comma separated list, an empty value clears it
+func listSetting(key string, target *[]string) setting {
+	return setting{key: key, set: func(value string) error {
+		list := []string{}
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+
+		*target = list
+		return nil
+	}}
+}
+
+func durationSetting(key string, target *Duration) setting {
+	return setting{key: key, set: func(value string) error {
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+
+		*target = Duration(duration)
+		return nil
+	}}
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloaded(t *testing.T) {
+	cfg := Default()
+
+	next := Default()
+	next.Port = cfg.Port + 1
+	next.MaxUsers = cfg.MaxUsers + 1
+	next.CleanupInterval = Duration(time.Minute)
+
+	reloaded := cfg.Reloaded(next)
+
+	assert.Equal(t, cfg.Port, reloaded.Port)
+	assert.Equal(t, next.MaxUsers, reloaded.MaxUsers)
+	assert.Equal(t, next.CleanupInterval, reloaded.CleanupInterval)
+}
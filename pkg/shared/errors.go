@@ -0,0 +1,113 @@
+package shared
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrorCode is the machine-readable code of an Error
+type ErrorCode string
+
+const (
+	CodeNotAvailable  ErrorCode = "notAvailable"
+	CodeNoPermission  ErrorCode = "noPermission"
+	CodeEmptyString   ErrorCode = "emptyString"
+	CodeTimeout       ErrorCode = "timeout"
+	CodeChannelClosed ErrorCode = "channelClosed"
+	CodeParsing       ErrorCode = "parsing"
+	CodeRateLimited   ErrorCode = "rateLimited"
+	// errors which don't wrap one of the sentinel errors
+	CodeInternal ErrorCode = "internal"
+)
+
+// errorCodes maps the sentinel errors to their code, the first match wins
+var errorCodes = []struct {
+	code      ErrorCode
+	err       error
+	retryable bool
+}{
+	{CodeRateLimited, ErrRateLimited, true},
+	{CodeNoPermission, ErrNoPermission, false},
+	{CodeChannelClosed, ErrChannelClosed, false},
+	{CodeTimeout, ErrTimeoutReached, true},
+	{CodeNotAvailable, ErrNotAvailable, false},
+	{CodeParsing, ErrParsing, false},
+	{CodeEmptyString, ErrEmptyString, false},
+}
+
+// Error is the error object of a response. It unwraps to the sentinel error of its code,
+// so it can be checked with errors.Is after it was received
+type Error struct {
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	// set if the same request may succeed later, like after a rate limit or timeout
+	Retryable bool              `json:"retryable"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	for _, c := range errorCodes {
+		if c.code == e.Code {
+			return c.err
+		}
+	}
+
+	return nil
+}
+
+// WithDetail adds a detail to the error and returns it
+func (e *Error) WithDetail(key string, value string) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]string)
+	}
+
+	e.Details[key] = value
+
+	return e
+}
+
+// DetailedError is implemented by errors which provide details for their Error
+type DetailedError interface {
+	ErrorDetails() map[string]string
+}
+
+// NewError converts err into an Error, the code is taken from the sentinel error it wraps
+func NewError(err error) *Error {
+	var rspErr *Error
+	if errors.As(err, &rspErr) {
+		return rspErr
+	}
+
+	rspErr = &Error{Code: CodeInternal, Message: err.Error()}
+
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			rspErr.Code = c.code
+			rspErr.Retryable = c.retryable
+			break
+		}
+	}
+
+	var detailed DetailedError
+	if errors.As(err, &detailed) {
+		rspErr.Details = detailed.ErrorDetails()
+	}
+
+	return rspErr
+}
+
+// ParseError is the compatibility shim for error strings of older servers, which
+// start with the message of the sentinel error they were built from
+func ParseError(message string) *Error {
+	for _, c := range errorCodes {
+		if strings.HasPrefix(message, c.err.Error()) {
+			return &Error{Code: c.code, Message: message, Retryable: c.retryable}
+		}
+	}
+
+	return &Error{Code: CodeInternal, Message: message}
+}
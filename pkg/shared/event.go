@@ -0,0 +1,371 @@
+package shared
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProtocolVersion is the version of the typed event envelope. Responses without
+// a version come from older servers and are classified by their flags with Upgrade
+const ProtocolVersion = 1
+
+// Kind is the type of an event, it decides which payload a response carries
+type Kind string
+
+const (
+	// ChatPayload, a broadcast, group or private message
+	KindChat Kind = "chat"
+	// NoticePayload, a notice of the server or of a group
+	KindNotice Kind = "notice"
+	// ResultPayload, the result of a plugin like /time
+	KindResult Kind = "result"
+	// TablePayload, a list which is rendered as table like /help
+	KindTable Kind = "table"
+	// UsersPayload
+	KindUsers Kind = "users"
+	// HistoryPayload
+	KindHistory Kind = "history"
+	// UserPayload of the client which joined or left
+	KindUserJoined Kind = "userJoined"
+	KindUserLeft   Kind = "userLeft"
+	// UserPayload of the client with its new and its old name
+	KindUserRenamed Kind = "userRenamed"
+	// GroupPayload of the joined group or of the left one with the reason for leaving
+	KindGroupJoined Kind = "groupJoined"
+	KindGroupLeft   Kind = "groupLeft"
+	// GroupPayload of the group which is active now, without group for the lobby
+	KindGroupSwitched Kind = "groupSwitched"
+	// GroupPayload of a renamed group or one with a new topic and the description of the change
+	KindGroupUpdated Kind = "groupUpdated"
+	// InvitePayload of an invitation into a group
+	KindGroupInvite Kind = "groupInvite"
+	// SignalPayload, a webRTC or call signal
+	KindSignal Kind = "signal"
+	// no payload
+	KindRegistered   Kind = "registered"
+	KindUnregistered Kind = "unregistered"
+	// Error
+	KindError Kind = "error"
+)
+
+type ChatPayload struct {
+	Sender   string `json:"sender"`
+	SenderId string `json:"senderId,omitempty"`
+	Content  string `json:"content"`
+	Private  bool   `json:"private,omitempty"`
+}
+
+type NoticePayload struct {
+	Text string `json:"text"`
+}
+
+type ResultPayload struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+type TablePayload struct {
+	Name string            `json:"name"`
+	Rows []json.RawMessage `json:"rows"`
+}
+
+type UsersPayload struct {
+	Clients []*JsonClient `json:"clients"`
+}
+
+type HistoryPayload struct {
+	Messages []*JsonMessage `json:"messages"`
+}
+
+type UserPayload struct {
+	ClientId string `json:"clientId"`
+	Name     string `json:"name"`
+	// only set if the client was renamed
+	OldName string `json:"oldName,omitempty"`
+}
+
+type GroupPayload struct {
+	Group  *JsonGroup `json:"group,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+}
+
+type InvitePayload struct {
+	Invite *JsonInvite `json:"invite"`
+}
+
+// SignalPayload contains the signal flag and its data like a sdp, an ice candidate or a call state,
+// the clientId of the opposite peer is the ClientId of the response
+type SignalPayload struct {
+	Signal string `json:"signal"`
+	Data   string `json:"data"`
+}
+
+// newEvent stamps the response, which carries the legacy fields, with the kind and payload
+func newEvent(rsp *Response, kind Kind, payload any) *Response {
+	rsp.Version = ProtocolVersion
+	rsp.Kind = kind
+
+	if rsp.Time.IsZero() {
+		rsp.Time = time.Now().UTC()
+	}
+
+	if payload == nil {
+		return rsp
+	}
+
+	// the payload types only consist of strings, bools and json, so encoding can't fail
+	data, err := json.Marshal(payload)
+	if err == nil {
+		rsp.Payload = data
+	}
+
+	return rsp
+}
+
+// jsonString encodes a legacy json content, empty lists are encoded as null like before
+func jsonString(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+
+	return string(data)
+}
+
+func ChatEvent(sender string, senderId string, content string, private bool) *Response {
+	name := sender
+	if private {
+		name = fmt.Sprintf("[%s]", sender)
+	}
+
+	return newEvent(&Response{RspName: name, Content: content, ClientId: senderId}, KindChat,
+		ChatPayload{Sender: sender, SenderId: senderId, Content: content, Private: private})
+}
+
+func NoticeEvent(text string) *Response {
+	return newEvent(&Response{Content: text}, KindNotice, NoticePayload{Text: text})
+}
+
+func ResultEvent(name string, content string) *Response {
+	return newEvent(&Response{RspName: name, Content: content}, KindResult, ResultPayload{Name: name, Content: content})
+}
+
+func TableEvent(name string, rows []json.RawMessage) *Response {
+	return newEvent(&Response{RspName: name, Content: jsonString(rows)}, KindTable, TablePayload{Name: name, Rows: rows})
+}
+
+func UsersEvent(clients []*JsonClient) *Response {
+	return newEvent(&Response{RspName: UsersFlag, Content: jsonString(clients)}, KindUsers, UsersPayload{Clients: clients})
+}
+
+func HistoryEvent(messages []*JsonMessage) *Response {
+	return newEvent(&Response{RspName: HistoryFlag, Content: jsonString(messages)}, KindHistory, HistoryPayload{Messages: messages})
+}
+
+func UserJoinedEvent(clientId string, name string) *Response {
+	return newEvent(&Response{RspName: UserAddFlag, Content: name, ClientId: clientId}, KindUserJoined,
+		UserPayload{ClientId: clientId, Name: name})
+}
+
+func UserLeftEvent(clientId string, name string) *Response {
+	return newEvent(&Response{RspName: UserRemoveFlag, Content: name, ClientId: clientId}, KindUserLeft,
+		UserPayload{ClientId: clientId, Name: name})
+}
+
+// UserRenamedEvent returns the new name of the client, which was called oldName before
+func UserRenamedEvent(clientId string, oldName string, name string) *Response {
+	return newEvent(&Response{RspName: UserRenameFlag, Content: name, ClientId: clientId}, KindUserRenamed,
+		UserPayload{ClientId: clientId, Name: name, OldName: oldName})
+}
+
+func GroupJoinedEvent(group *JsonGroup) *Response {
+	return newEvent(&Response{RspName: AddGroupFlag, Content: jsonString(group)}, KindGroupJoined, GroupPayload{Group: group})
+}
+
+func GroupLeftEvent(group *JsonGroup, reason string) *Response {
+	return newEvent(&Response{RspName: LeaveGroupFlag, Content: reason}, KindGroupLeft, GroupPayload{Group: group, Reason: reason})
+}
+
+// GroupSwitchedEvent returns the group which is active now, group is nil for the lobby
+func GroupSwitchedEvent(group *JsonGroup) *Response {
+	content := ""
+	if group != nil {
+		content = jsonString(group)
+	}
+
+	return newEvent(&Response{RspName: SwitchGroupFlag, Content: content}, KindGroupSwitched, GroupPayload{Group: group})
+}
+
+// GroupUpdatedEvent returns the changed group, reason describes the change
+func GroupUpdatedEvent(group *JsonGroup, reason string) *Response {
+	return newEvent(&Response{RspName: UpdateGroupFlag, Content: reason}, KindGroupUpdated, GroupPayload{Group: group, Reason: reason})
+}
+
+func GroupInviteEvent(invite *JsonInvite) *Response {
+	return newEvent(&Response{RspName: GroupInviteFlag, Content: jsonString(invite), ClientId: invite.InviterId}, KindGroupInvite,
+		InvitePayload{Invite: invite})
+}
+
+// SignalEvent returns a signal of the client with clientId
+func SignalEvent(signal string, clientId string, data string) *Response {
+	return newEvent(&Response{RspName: signal, Content: data, ClientId: clientId}, KindSignal,
+		SignalPayload{Signal: signal, Data: data})
+}
+
+func RegisteredEvent() *Response {
+	return newEvent(&Response{Content: RegisterFlag}, KindRegistered, nil)
+}
+
+func UnregisteredEvent(name string) *Response {
+	return newEvent(&Response{RspName: name, Content: UnregisterFlag}, KindUnregistered, nil)
+}
+
+// ErrorEvent returns the error as response, the error string is kept in Err for older clients
+func ErrorEvent(err error) *Response {
+	rspErr := NewError(err)
+	return newEvent(&Response{Err: rspErr.Message, Error: rspErr}, KindError, rspErr)
+}
+
+// DecodePayload decodes the payload of a typed response into v
+func (rsp *Response) DecodePayload(v any) error {
+	if len(rsp.Payload) == 0 {
+		return fmt.Errorf("%w: %s response has no payload", ErrParsing, rsp.Kind)
+	}
+
+	return json.Unmarshal(rsp.Payload, v)
+}
+
+// Upgrade is the compatibility shim for responses without a version. It classifies them by the
+// flags of the current format and converts the content into the payload of the kind
+func Upgrade(rsp *Response) *Response {
+	if rsp.Version >= ProtocolVersion && rsp.Kind != "" {
+		return rsp
+	}
+
+	kind := LegacyKind(rsp)
+
+	switch kind {
+	case KindError:
+		rsp.Error = ParseError(rsp.Err)
+		return newEvent(rsp, kind, rsp.Error)
+
+	case KindUsers:
+		clients, err := ParseJsonToJsonClients(rsp.Content)
+		if err != nil {
+			return legacyChat(rsp)
+		}
+		return newEvent(rsp, kind, UsersPayload{Clients: clients})
+
+	case KindHistory:
+		messages, err := ParseJsonToJsonMessages(rsp.Content)
+		if err != nil {
+			return legacyChat(rsp)
+		}
+		return newEvent(rsp, kind, HistoryPayload{Messages: messages})
+
+	case KindTable:
+		var rows []json.RawMessage
+		err := json.Unmarshal([]byte(rsp.Content), &rows)
+		if err != nil {
+			return legacyChat(rsp)
+		}
+		return newEvent(rsp, kind, TablePayload{Name: rsp.RspName, Rows: rows})
+
+	case KindUserJoined, KindUserLeft, KindUserRenamed:
+		return newEvent(rsp, kind, UserPayload{ClientId: rsp.ClientId, Name: rsp.Content})
+
+	case KindGroupJoined:
+		group, err := DecodeStringToJsonGroup(rsp.Content)
+		if err != nil {
+			return legacyChat(rsp)
+		}
+		return newEvent(rsp, kind, GroupPayload{Group: group})
+
+	case KindGroupLeft, KindGroupUpdated:
+		return newEvent(rsp, kind, GroupPayload{Reason: rsp.Content})
+
+	case KindGroupSwitched:
+		if rsp.Content == "" {
+			return newEvent(rsp, kind, GroupPayload{})
+		}
+
+		group, err := DecodeStringToJsonGroup(rsp.Content)
+		if err != nil {
+			return legacyChat(rsp)
+		}
+		return newEvent(rsp, kind, GroupPayload{Group: group})
+
+	case KindGroupInvite:
+		invite := &JsonInvite{}
+		err := json.Unmarshal([]byte(rsp.Content), invite)
+		if err != nil || invite.Group == nil {
+			return legacyChat(rsp)
+		}
+		return newEvent(rsp, kind, InvitePayload{Invite: invite})
+
+	case KindSignal:
+		return newEvent(rsp, kind, SignalPayload{Signal: rsp.RspName, Data: rsp.Content})
+
+	case KindNotice:
+		return newEvent(rsp, kind, NoticePayload{Text: rsp.Content})
+
+	case KindChat:
+		return legacyChat(rsp)
+	}
+
+	return newEvent(rsp, kind, nil)
+}
+
+// LegacyKind classifies a response without a version by the flags in its name and content
+func LegacyKind(rsp *Response) Kind {
+	switch {
+	case rsp.Err != "":
+		return KindError
+	case rsp.RspName == UsersFlag:
+		return KindUsers
+	case rsp.RspName == HistoryFlag:
+		return KindHistory
+	case rsp.Content == RegisterFlag:
+		return KindRegistered
+	case rsp.Content == UnregisterFlag:
+		return KindUnregistered
+	case rsp.RspName == "":
+		return KindNotice
+	case rsp.RspName == UserRemoveFlag:
+		return KindUserLeft
+	case rsp.RspName == UserAddFlag:
+		return KindUserJoined
+	case rsp.RspName == UserRenameFlag:
+		return KindUserRenamed
+	case rsp.RspName == AddGroupFlag:
+		return KindGroupJoined
+	case rsp.RspName == LeaveGroupFlag:
+		return KindGroupLeft
+	case rsp.RspName == GroupInviteFlag:
+		return KindGroupInvite
+	case rsp.RspName == SwitchGroupFlag:
+		return KindGroupSwitched
+	case rsp.RspName == UpdateGroupFlag:
+		return KindGroupUpdated
+	case rsp.RspName == FailedConnectionFlag, rsp.RspName == OfferSignalFlag, rsp.RspName == AnswerSignalFlag,
+		rsp.RspName == ICECandidateFlag, rsp.RspName == InitializeSignalFlag:
+		return KindSignal
+	case strings.HasPrefix(rsp.Content, "["):
+		return KindTable
+	}
+
+	return KindChat
+}
+
+// legacyChat converts a legacy response into a chat message, this is also the fallback
+// if the content of a flagged response can't be parsed, since a sender may be named like a flag
+func legacyChat(rsp *Response) *Response {
+	sender, private := strings.CutPrefix(rsp.RspName, "[")
+	if private {
+		sender = strings.TrimSuffix(sender, "]")
+	}
+
+	return newEvent(rsp, KindChat, ChatPayload{Sender: sender, SenderId: rsp.ClientId, Content: rsp.Content, Private: private})
+}
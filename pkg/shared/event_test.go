@@ -0,0 +1,95 @@
+package shared
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgrade(t *testing.T) {
+	group, err := json.Marshal(&JsonGroup{GroupId: "groupId", Name: "Room"})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name string
+		rsp  *Response
+		kind Kind
+	}{
+		{"error", &Response{Err: ErrNotAvailable.Error() + ": gone"}, KindError},
+		{"notice", &Response{Content: "server restarts"}, KindNotice},
+		{"registered", &Response{RspName: "Arndt", Content: RegisterFlag}, KindRegistered},
+		{"unregistered", &Response{Content: UnregisterFlag}, KindUnregistered},
+		{"users", &Response{RspName: UsersFlag, Content: `[{"name": "Arndt"}]`}, KindUsers},
+		{"history", &Response{RspName: HistoryFlag, Content: `[{"sender": "Arndt", "content": "hi"}]`}, KindHistory},
+		{"table", &Response{RspName: "Help", Content: `[{"plugin": "/help"}]`}, KindTable},
+		{"user joined", &Response{RspName: UserAddFlag, Content: "Arndt", ClientId: "clientId"}, KindUserJoined},
+		{"user left", &Response{RspName: UserRemoveFlag, Content: "Arndt", ClientId: "clientId"}, KindUserLeft},
+		{"group joined", &Response{RspName: AddGroupFlag, Content: string(group)}, KindGroupJoined},
+		{"group left", &Response{RspName: LeaveGroupFlag, Content: "kicked"}, KindGroupLeft},
+		{"lobby", &Response{RspName: SwitchGroupFlag}, KindGroupSwitched},
+		{"signal", &Response{RspName: OfferSignalFlag, Content: "sdp", ClientId: "clientId"}, KindSignal},
+		{"chat", &Response{RspName: "Arndt", Content: "hi"}, KindChat},
+		// users may be named like a flag or write content looking like the data of one
+		{"sender named like a flag", &Response{RspName: UsersFlag, Content: "hi"}, KindChat},
+		{"sender named like the history", &Response{RspName: HistoryFlag, Content: "[not json"}, KindChat},
+		{"content looking like a table", &Response{RspName: "Arndt", Content: "[afk] back soon"}, KindChat},
+		{"content looking like a group", &Response{RspName: AddGroupFlag, Content: "{broken"}, KindChat},
+	}
+
+	for _, test := range tests {
+		rsp := Upgrade(test.rsp)
+		assert.Equal(t, test.kind, rsp.Kind, test.name)
+		assert.Equal(t, ProtocolVersion, rsp.Version, test.name)
+		assert.False(t, rsp.Time.IsZero(), test.name)
+	}
+}
+
+func TestUpgradeKeepsVersionedResponses(t *testing.T) {
+	// the content of a typed response is never classified by flags
+	rsp := ChatEvent(UsersFlag, "clientId", RegisterFlag, false)
+	assert.Equal(t, KindChat, Upgrade(rsp).Kind)
+
+	var chat ChatPayload
+	assert.Nil(t, rsp.DecodePayload(&chat))
+	assert.Equal(t, ChatPayload{Sender: UsersFlag, SenderId: "clientId", Content: RegisterFlag}, chat)
+}
+
+func TestUpgradePayloads(t *testing.T) {
+	private := Upgrade(&Response{RspName: "[Arndt]", Content: "psst", ClientId: "clientId"})
+
+	var chat ChatPayload
+	assert.Nil(t, private.DecodePayload(&chat))
+	assert.Equal(t, ChatPayload{Sender: "Arndt", SenderId: "clientId", Content: "psst", Private: true}, chat)
+
+	var users UsersPayload
+	assert.Nil(t, Upgrade(&Response{RspName: UsersFlag, Content: `[{"name": "Arndt"}]`}).DecodePayload(&users))
+	if assert.Len(t, users.Clients, 1) {
+		assert.Equal(t, "Arndt", users.Clients[0].Name)
+	}
+
+	var signal SignalPayload
+	assert.Nil(t, Upgrade(&Response{RspName: ICECandidateFlag, Content: "candidate"}).DecodePayload(&signal))
+	assert.Equal(t, SignalPayload{Signal: ICECandidateFlag, Data: "candidate"}, signal)
+
+	failed := Upgrade(&Response{Err: ErrNotAvailable.Error() + ": gone"})
+	assert.ErrorIs(t, failed.Error, ErrNotAvailable)
+}
+
+func TestDecodePayload(t *testing.T) {
+	var notice NoticePayload
+	assert.Nil(t, NoticeEvent("hello").DecodePayload(&notice))
+	assert.Equal(t, "hello", notice.Text)
+
+	// events without payload and payloads of another type can't be decoded
+	assert.ErrorIs(t, RegisteredEvent().DecodePayload(&notice), ErrParsing)
+
+	assert.NotNil(t, NoticeEvent("hello").DecodePayload(&struct {
+		Text int `json:"text"`
+	}{}))
+
+	// switching to the lobby has a payload without group
+	var group GroupPayload
+	assert.Nil(t, GroupSwitchedEvent(nil).DecodePayload(&group))
+	assert.Nil(t, group.Group)
+}
@@ -4,7 +4,10 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"slices"
 	"strings"
+	"unicode"
 )
 
 // generateSecureToken generates a token containing random chars
@@ -17,6 +20,29 @@ func GenerateSecureToken(length int) string {
 	return base64.RawURLEncoding.EncodeToString(b)
 }
 
+// ParseTokenClaims decodes the claims of a session token without verifying its signature,
+// the token consists of the base64 encoded claims and signature separated by a dot
+func ParseTokenClaims(token string) (*TokenClaims, error) {
+	payload, _, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, fmt.Errorf("%w: token has no signature", ErrParsing)
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token payload couldn't be decoded", ErrParsing)
+	}
+
+	claims := &TokenClaims{}
+
+	err = json.Unmarshal(data, claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token claims couldn't be parsed", ErrParsing)
+	}
+
+	return claims, nil
+}
+
 // DecodeToResponse decodes a responseBody to a Response struct
 func DecodeToResponse(body []byte) (*Response, error) {
 	response := &Response{}
@@ -30,6 +56,25 @@ func DecodeToResponse(body []byte) (*Response, error) {
 	return response, nil
 }
 
+// DecodeToResponses decodes a responseBody containing either a single Response
+// or a json array of Responses to a Response slice
+func DecodeToResponses(body []byte) ([]*Response, error) {
+	if !strings.HasPrefix(strings.TrimSpace(string(body)), "[") {
+		response, err := DecodeToResponse(body)
+		return []*Response{response}, err
+	}
+
+	var responses []*Response
+	dec := json.NewDecoder(strings.NewReader(string(body)))
+
+	err := dec.Decode(&responses)
+	if err != nil {
+		return nil, err
+	}
+
+	return responses, nil
+}
+
 // DecodeToMessage decodes a responseBody to a Message struct
 func DecodeToMessage(body []byte) (Message, error) {
 	message := Message{}
@@ -64,6 +109,18 @@ func DecodeStringToJsonGroup(jsonGroup string) (*JsonGroup, error) {
 	return group, err
 }
 
+func ParseJsonToJsonMessages(jsonSlice string) ([]*JsonMessage, error) {
+	var messages []*JsonMessage
+	dec := json.NewDecoder(strings.NewReader(jsonSlice))
+
+	err := dec.Decode(&messages)
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
 func ParseJsonToJsonClients(jsonSlice string) ([]*JsonClient, error) {
 	var clients []*JsonClient
 	dec := json.NewDecoder(strings.NewReader(jsonSlice))
@@ -83,3 +140,57 @@ func DecodeStringToJsonClient(jsonClient string) (*JsonClient, error) {
 
 	return client, err
 }
+
+// SplitGroupOptions splits the content of '/group create {name} [--public|--unlisted|--private] [--password {password}]'
+// into its parts. The options follow the name and start with --, so every word of the name is kept,
+// the visibility is PublicGroup if none is given
+func SplitGroupOptions(content string) (string, string, string, error) {
+	fields := strings.Fields(content)
+
+	i := slices.IndexFunc(fields, func(field string) bool { return strings.HasPrefix(field, "--") })
+	if i < 0 {
+		i = len(fields)
+	}
+
+	name, visibility, password := strings.Join(fields[:i], " "), PublicGroup, ""
+
+	for options := fields[i:]; len(options) > 0; options = options[1:] {
+		switch options[0] {
+		case "--" + PublicGroup, "--" + UnlistedGroup, "--" + PrivateGroup:
+			visibility = strings.TrimPrefix(options[0], "--")
+		case "--password":
+			if len(options) < 2 {
+				return "", "", "", fmt.Errorf("%w: --password expects a password", ErrParsing)
+			}
+
+			options = options[1:]
+			password = options[0]
+		default:
+			return "", "", "", fmt.Errorf("%w: unknown option %s", ErrParsing, options[0])
+		}
+	}
+
+	return name, visibility, password, nil
+}
+
+// limits of a client name, checked by the server and the client
+const MinNameLength = 3
+const MaxNameLength = 50
+
+// CheckName returns an error if the name is too short or too long, contains whitespace
+// or starts with @, which addresses clients by name
+func CheckName(name string) error {
+	if len(name) < MinNameLength || len(name) > MaxNameLength {
+		return fmt.Errorf("%w: the name has to be between %d and %d chars long", ErrParsing, MinNameLength, MaxNameLength)
+	}
+
+	if strings.ContainsFunc(name, unicode.IsSpace) {
+		return fmt.Errorf("%w: the name mustn't contain spaces", ErrParsing)
+	}
+
+	if strings.HasPrefix(name, "@") {
+		return fmt.Errorf("%w: the name mustn't start with @", ErrParsing)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitGroupOptions(t *testing.T) {
+	tests := []struct {
+		content    string
+		name       string
+		visibility string
+		password   string
+	}{
+		{"room", "room", PublicGroup, ""},
+		{"a private room", "a private room", PublicGroup, ""},
+		{"my team --unlisted", "my team", UnlistedGroup, ""},
+		{"my team --private --password secret", "my team", PrivateGroup, "secret"},
+		{"my team --password secret --private", "my team", PrivateGroup, "secret"},
+		{"room --password secret", "room", PublicGroup, "secret"},
+	}
+
+	for _, test := range tests {
+		name, visibility, password, err := SplitGroupOptions(test.content)
+		assert.Nil(t, err, test.content)
+		assert.Equal(t, test.name, name, test.content)
+		assert.Equal(t, test.visibility, visibility, test.content)
+		assert.Equal(t, test.password, password, test.content)
+	}
+
+	for _, content := range []string{"room --password", "room --hidden", "room --private private"} {
+		_, _, _, err := SplitGroupOptions(content)
+		assert.ErrorIs(t, err, ErrParsing, content)
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	assert.Nil(t, CheckName("Arndt"))
+	assert.Nil(t, CheckName("Ar@ndt"))
+
+	for _, name := range []string{"", "ab", strings.Repeat("x", MaxNameLength+1), "Arndt Len", "Arndt\tLen", "@Arndt"} {
+		assert.ErrorIs(t, CheckName(name), ErrParsing, name)
+	}
+}
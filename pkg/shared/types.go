@@ -1,7 +1,9 @@
 package shared
 
 import (
+	"encoding/json"
 	"errors"
+	"time"
 )
 
 // routes
@@ -11,6 +13,9 @@ const (
 	Delete
 	Get
 	SignalWebRTC
+	WebSocket
+	Resume
+	RefreshToken
 )
 
 // muteable device
@@ -21,11 +26,16 @@ const UnregisterFlag = "- Du bist nun vom Server getrennt -"
 const RegisterFlag = "- Du bist registriert -"
 const AddGroupFlag = "Add Group"
 const LeaveGroupFlag = "Leave Group"
+const GroupInviteFlag = "Group Invite"
+const SwitchGroupFlag = "Switch Group"
+const UpdateGroupFlag = "Update Group"
 
 const UsersFlag = "Users"
+const HistoryFlag = "History"
 const IgnoreResponseTag = "Ignore Response"
 const UserAddFlag = "Add User"
 const UserRemoveFlag = "Remove User"
+const UserRenameFlag = "Rename User"
 
 // signal flags
 const ICECandidateFlag = "ICE Candidate"
@@ -50,10 +60,12 @@ var (
 	ErrTimeoutReached error = errors.New("timeout was reached")
 	ErrChannelClosed  error = errors.New("access")
 	ErrParsing        error = errors.New("the input couldn't be parsed")
+	ErrRateLimited    error = errors.New("too many requests")
 )
 
 // Message contains the name and id of the requester and the message (content) itsself
-// as well as the uses plugin and groupId if a user is in a group
+// as well as the uses plugin and groupId of the group it is addressed to, messages
+// without groupId are addressed to the active group of the client
 type Message struct {
 	Name     string `json:"name"`
 	Content  string `json:"content"`
@@ -63,21 +75,97 @@ type Message struct {
 }
 
 // Response contains the name and id of the sender, the response (content) itsself
-// and an error string
+// and an error string. Since ProtocolVersion 1 it also carries the Kind of the event
+// with a typed payload, the other fields are kept for older clients. Seq is the per-client
+// sequence number assigned on delivery, it is 0 if the response wasn't queued. GroupId is set
+// if the response belongs to a group, since a client can be member of several ones
 type Response struct {
-	ClientId string `json:"clientId"`
-	RspName  string `json:"name"`
-	Content  string `json:"content"`
-	Err      string `json:"errorString"`
+	ClientId string          `json:"clientId"`
+	RspName  string          `json:"name"`
+	Content  string          `json:"content"`
+	Err      string          `json:"errorString"`
+	Error    *Error          `json:"error,omitempty"`
+	Version  int             `json:"version,omitempty"`
+	Kind     Kind            `json:"kind,omitempty"`
+	Payload  json.RawMessage `json:"payload,omitempty"`
+	Seq      uint64          `json:"seq,omitempty"`
+	Time     time.Time       `json:"time,omitzero"`
+	GroupId  string          `json:"groupId,omitempty"`
+}
+
+// TokenClaims are the signed claims of a session token, times are unix seconds
+type TokenClaims struct {
+	ClientId  string `json:"cid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	Id        string `json:"jti"`
+}
+
+// JsonSession contains everything a client needs to resume its session after
+// a lost connection or a restart
+type JsonSession struct {
+	ClientId  string     `json:"clientId"`
+	Name      string     `json:"name"`
+	AuthToken string     `json:"authToken"`
+	Group     *JsonGroup `json:"group,omitempty"`
+	// every group the client is a member of, Group is the active one
+	Groups []*JsonGroup `json:"groups,omitempty"`
+	// sequence number of the last received response, only kept in the session file of the client
+	LastSeq uint64 `json:"lastSeq,omitempty"`
 }
 
 // JsonGroup contains an id the groupname and the size of the group
 // Notice that there is another Group struct in groupRegistry which
 // has some extra fields which are used for server internal logic
 type JsonGroup struct {
-	GroupId string `json:"groupId"`
-	Name    string `json:"name"`
-	Size    int    `json:"size"`
+	GroupId    string `json:"groupId"`
+	Name       string `json:"name"`
+	Size       int    `json:"size"`
+	Visibility string `json:"visibility,omitempty"`
+	// set if joining requires a password
+	Protected bool `json:"protected,omitempty"`
+	// set if the group is kept while empty and across restarts
+	Persistent bool `json:"persistent,omitempty"`
+	// topic or description of the group set by its moderators
+	Topic string `json:"topic,omitempty"`
+}
+
+// group visibilities, public groups are listed, unlisted ones can be joined by their id
+// and private ones only with an invitation
+const PublicGroup = "public"
+const UnlistedGroup = "unlisted"
+const PrivateGroup = "private"
+
+// JsonInvite is a pending invitation into a group, it expires at Expires
+type JsonInvite struct {
+	Group     *JsonGroup `json:"group"`
+	InviterId string     `json:"inviterId"`
+	Inviter   string     `json:"inviter"`
+	Expires   time.Time  `json:"expires"`
+}
+
+// group roles
+const OwnerRole = "owner"
+const ModeratorRole = "moderator"
+const MemberRole = "member"
+
+// message history scopes
+const LobbyScope = "lobby"
+const GroupScope = "group"
+const PrivateScope = "private"
+
+// JsonMessage is a recorded chat message which can be replayed as history
+type JsonMessage struct {
+	Scope      string    `json:"scope"`
+	GroupId    string    `json:"groupId,omitempty"`
+	Sender     string    `json:"sender"`
+	SenderId   string    `json:"senderId,omitempty"`
+	ReceiverId string    `json:"receiverId,omitempty"`
+	Content    string    `json:"content"`
+	Time       time.Time `json:"time"`
+	// accounts of the participants of a private message, so it is replayed in their later sessions
+	SenderAccount   string `json:"senderAccount,omitempty"`
+	ReceiverAccount string `json:"receiverAccount,omitempty"`
 }
 
 type JsonClient struct {
@@ -86,6 +174,8 @@ type JsonClient struct {
 	ClientId  string `json:"clientId"`
 	GroupName string `json:"groupName"`
 	GroupId   string `json:"groupId"`
+	// role in the group, only set in the users of a group
+	Role string `json:"role,omitempty"`
 }
 
 // signals, that a state of a client or the list of clients has changed